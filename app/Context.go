@@ -8,7 +8,17 @@ Created by Thomas.Cherry.gmail.com
 
 package app
 
-import "os"
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/jceaser/ollama-query/client"
+)
 
 /**************************************/
 // MARK: - Marshal functions
@@ -18,8 +28,79 @@ type Action func(context AppContext, args ...string) (map[string]string, error)
 
 type AppContext struct {
 	HostName string
+	Client   *client.Client
 	Output   *os.File
 	Error    *os.File
 	Context  []int
 	Verbose  int
+	Profile  string // name of the profile from the configuration file, if any
+	System   string // system prompt sent with generate and new chats
+	Format   string // output format, one of OutputFormats, empty is the same as table
+	Render   string // how answers are printed, one of RenderModes, empty is the same as raw
+	Model    string // default model from the profile, used when a command leaves the model off
+
+	Conversation *Conversation // chat history shared by all chat commands
+	Options      Options       // sent with every generate and chat request
+	Tools        *Toolbox      // tools the model may call during a chat
+	Attachments  *Attachments  // images to send with the next prompt
+	Last         *LastAnswer   // the most recent answer, for pulling code out of
+	Stats        *SessionStats // totals for every answer, and if a footer is shown after each
+
+	Ask func(prompt string) (string, error) // reads an answer from the user, nil when not interactive
+
+	ctx context.Context // cancelled when the command is interrupted, see WithCtx
+}
+
+// ErrInterrupted is returned by actions which were stopped with Ctrl-C part way through
+var ErrInterrupted = errors.New("interrupted")
+
+// Api returns the client for HostName, building a new one if none was set or the host has changed
+func (c AppContext) Api() *client.Client {
+	if c.Client == nil || c.Client.BaseURL != strings.TrimRight(c.HostName, "/") {
+		return client.New(c.HostName)
+	}
+	return c.Client
+}
+
+// Ctx returns the go context which requests made by an action should use
+func (c AppContext) Ctx() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// WithCtx returns a copy of the app context whose requests use ctx
+func (c AppContext) WithCtx(ctx context.Context) AppContext {
+	c.ctx = ctx
+	return c
+}
+
+// Interruptible returns a go context which is cancelled when the user presses Ctrl-C, call stop
+// once the action is done to restore the default signal handling.
+func (c AppContext) Interruptible() (ctx context.Context, stop context.CancelFunc) {
+	return signal.NotifyContext(c.Ctx(), os.Interrupt)
+}
+
+// Flags returns a flag set for parsing the options given to an action, errors are reported to
+// the caller instead of exiting.
+func (c AppContext) Flags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	if c.Error != nil {
+		flags.SetOutput(c.Error)
+	}
+	return flags
+}
+
+// Confirm asks a yes or no question, an error is returned if there is no way to ask the user
+func (c AppContext) Confirm(question string) (bool, error) {
+	if c.Ask == nil {
+		return false, fmt.Errorf("can not ask [%s] without a terminal", question)
+	}
+	answer, err := c.Ask(question + " [y/N] ")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
 }
@@ -8,33 +8,16 @@ Created by Thomas.Cherry.gmail.com
 
 package app
 
-// Model represents an individual model.
-type Model struct {
-	Name          string  `json:"name"`
-	Model         string  `json:"model"`
-	Size          int64   `json:"size"`
-	Digest        string  `json:"digest"`
-	Details       Details `json:"details"`
-	ExpiresAt     string  `json:"expires_at"`
-	SizeVRAM      int64   `json:"size_vram"`
-	ContextLength int64   `json:"context_length"`
-}
+import "github.com/jceaser/ollama-query/client"
 
-// Details contains additional information about the model.
-type Details struct {
-	ParentModel       string   `json:"parent_model"`
-	Format            string   `json:"format"`
-	Family            string   `json:"family"`
-	Families          []string `json:"families"`
-	ParameterSize     string   `json:"parameter_size"`
-	QuantizationLevel string   `json:"quantization_level"`
-}
-
-// ModelsResponse represents the overall structure of the JSON response.
-type ModelsResponse struct {
-	Models []Model `json:"models"`
-}
-
-type VersionResponse struct {
-	Version string `json:"version"`
-}
+// The API structures now live in the client package, these names are kept for existing callers.
+type (
+	Model            = client.Model
+	Details          = client.Details
+	ModelsResponse   = client.ModelsResponse
+	VersionResponse  = client.VersionResponse
+	Modelfile        = client.ShowResponse
+	ResponseFromJson = client.GenerateResponse
+	ChatResponse     = client.ChatResponse
+	Message          = client.Message
+)
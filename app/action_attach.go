@@ -0,0 +1,43 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Command to attach images to the next generate or chat prompt.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"fmt"
+)
+
+// Attach queues images for the next prompt, lists them, or clears them
+func Attach(context AppContext, args ...string) (map[string]string, error) {
+	if context.Attachments == nil {
+		return nil, fmt.Errorf("attachments are not available")
+	}
+	attachments := context.Attachments
+
+	switch {
+	case len(args) == 0:
+		if len(attachments.Images) == 0 {
+			fmt.Fprintln(context.Output, "Nothing attached.")
+		}
+		for i, path := range attachments.Images {
+			fmt.Fprintf(context.Output, "%2d %s\n", i+1, path)
+		}
+	case args[0] == "clear":
+		attachments.Clear()
+		fmt.Fprintln(context.Output, "Attachments removed.")
+	default:
+		for _, path := range args {
+			if _, err := checkImage(path); err != nil {
+				return nil, err
+			}
+			attachments.Images = append(attachments.Images, path)
+		}
+		fmt.Fprintf(context.Output, "%d image(s) will be sent with the next prompt.\n", len(attachments.Images))
+	}
+	return nil, nil
+}
@@ -0,0 +1,340 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Benchmarks models against each other by running a set of prompts through /api/generate and
+comparing how fast each answers.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jceaser/ollama-query/client"
+	"github.com/jceaser/ollama-query/lib"
+)
+
+// BenchRun is the result of sending one prompt to one model
+type BenchRun struct {
+	Model        string  `json:"model"`
+	Prompt       int     `json:"prompt"` // which prompt in the file, from 1
+	Run          int     `json:"run"`
+	PromptTokens int     `json:"prompt_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	PromptRate   float64 `json:"prompt_tokens_per_second"`
+	OutputRate   float64 `json:"output_tokens_per_second"`
+	Load         float64 `json:"load_ms"`
+	FirstToken   float64 `json:"first_token_ms"`
+	Wall         float64 `json:"wall_ms"`
+}
+
+// BenchSummary compares the runs of one model
+type BenchSummary struct {
+	Model      string  `json:"model"`
+	Runs       int     `json:"runs"`
+	Rated      int     `json:"rated_runs"` // runs with timings, the only ones in the token rates
+	Mean       float64 `json:"mean_tokens_per_second"`
+	P50        float64 `json:"p50_tokens_per_second"`
+	P95        float64 `json:"p95_tokens_per_second"`
+	Load       float64 `json:"mean_load_ms"`
+	FirstToken float64 `json:"mean_first_token_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// BenchReport is everything a benchmark found, as written by -export and the json and yaml formats
+type BenchReport struct {
+	Summaries []BenchSummary `json:"summaries"`
+	Runs      []BenchRun     `json:"runs"`
+}
+
+/*
+Bench sends each prompt in a file to each model, a number of times, and compares the tokens per
+second and load times. The prompt file has one prompt per line, blank lines and lines starting with
+# are skipped.
+
+	bench -n 3 -unload prompts.txt llama3 mistral
+*/
+func Bench(context AppContext, args ...string) (map[string]string, error) {
+	usage := "Usage: bench [-n runs] [-unload] [-export file.csv|file.json] <prompt-file> <model...>"
+	flags := context.Flags("bench")
+	runs := flags.Int("n", 3, "number of times to send each prompt to each model")
+	unload := flags.Bool("unload", false, "unload the model after each run so every run includes loading it")
+	export := flags.String("export", "", "also write the results to a .csv or .json file")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() < 2 {
+		return nil, fmt.Errorf("a prompt file and at least one model are needed. %s", usage)
+	}
+	if *runs < 1 {
+		return nil, fmt.Errorf("the number of runs must be at least 1. %s", usage)
+	}
+	exportFormat := strings.ToLower(strings.TrimPrefix(filepath.Ext(*export), "."))
+	if *export != "" && exportFormat != "csv" && exportFormat != "json" {
+		return nil, fmt.Errorf("can only export to a .csv or .json file, not [%s]. %s", *export, usage)
+	}
+	prompts, err := readPrompts(flags.Arg(0))
+	if err != nil {
+		return nil, err
+	}
+
+	structured := context.Structured()
+	if !structured {
+		fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+		fmt.Fprintf(context.Output, "Benchmarking %d model(s) with %d prompt(s), %d run(s) each\n",
+			flags.NArg()-1, len(prompts), *runs)
+	}
+
+	var report BenchReport
+	var interrupted error
+	for _, model := range flags.Args()[1:] {
+		summary := BenchSummary{Model: model}
+		var modelRuns []BenchRun
+	prompts:
+		for p, prompt := range prompts {
+			for run := 1; run <= *runs; run++ {
+				result, err := benchRun(context, model, prompt, *unload)
+				if err != nil {
+					if context.Ctx().Err() != nil {
+						interrupted = ErrInterrupted
+					} else {
+						summary.Error = err.Error()
+						lib.Log.Warn.Printf("Skipping %s: %v\n", model, err)
+					}
+					break prompts
+				}
+				result.Prompt, result.Run = p+1, run
+				modelRuns = append(modelRuns, result)
+				if !structured {
+					fmt.Fprintf(context.Output, "  %-24s prompt %d run %d: %6.1f tok/s, %4d tokens\n",
+						model, p+1, run, result.OutputRate, result.OutputTokens)
+				}
+			}
+		}
+		report.Runs = append(report.Runs, modelRuns...)
+		report.Summaries = append(report.Summaries, summarizeRuns(summary, modelRuns))
+		if interrupted != nil {
+			break
+		}
+	}
+
+	var written []string
+	if *export != "" {
+		if written, err = exportBench(*export, exportFormat, report); err != nil {
+			return nil, err
+		}
+	}
+	if structured {
+		if err := writeDocument(context, report); err != nil {
+			return nil, err
+		}
+		return nil, interrupted
+	}
+	writeBenchTable(context, report.Summaries)
+	if len(written) > 0 {
+		fmt.Fprintf(context.Output, "Results written to %s.\n", strings.Join(written, " and "))
+	}
+	return nil, interrupted
+}
+
+// readPrompts returns the prompts in a file, one to a line
+func readPrompts(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var prompts []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			prompts = append(prompts, line)
+		}
+	}
+	if len(prompts) == 0 {
+		return nil, fmt.Errorf("no prompts found in %s", path)
+	}
+	return prompts, nil
+}
+
+// benchRun sends one prompt on its own, without the system prompt or context of the session, and
+// times the answer
+func benchRun(context AppContext, model, prompt string, unload bool) (BenchRun, error) {
+	request := &client.GenerateRequest{
+		Model:  model,
+		Prompt: prompt,
+
+		Options:   context.Options.Model(),
+		KeepAlive: context.Options.KeepAlive(),
+	}
+	if unload {
+		request.KeepAlive = 0
+	}
+	stats := startStats()
+	err := context.Api().Generate(context.Ctx(), request, func(response ResponseFromJson) error {
+		if response.Response != "" {
+			stats.Token()
+		}
+		if response.Done {
+			stats.Finish(response.Metrics)
+		}
+		return nil
+	})
+	if err != nil {
+		return BenchRun{}, err
+	}
+	return BenchRun{
+		Model:        model,
+		PromptTokens: stats.PromptEvalCount,
+		OutputTokens: stats.EvalCount,
+		PromptRate:   perSecond(stats.PromptEvalCount, stats.PromptEvalDuration),
+		OutputRate:   perSecond(stats.EvalCount, stats.EvalDuration),
+		Load:         milliseconds(time.Duration(stats.LoadDuration)),
+		FirstToken:   milliseconds(stats.FirstToken),
+		Wall:         milliseconds(stats.Wall),
+	}, nil
+}
+
+// summarizeRuns works out the mean and percentiles of the output tokens per second. Runs the server
+// sent no timings for have a rate of zero, which would only drag the numbers down, so they are
+// left out of the rates.
+func summarizeRuns(summary BenchSummary, runs []BenchRun) BenchSummary {
+	summary.Runs = len(runs)
+	if len(runs) == 0 {
+		return summary
+	}
+	var rates []float64
+	for _, run := range runs {
+		if run.OutputRate > 0 {
+			rates = append(rates, run.OutputRate)
+			summary.Mean += run.OutputRate
+		}
+		summary.Load += run.Load
+		summary.FirstToken += run.FirstToken
+	}
+	count := float64(len(runs))
+	summary.Load /= count
+	summary.FirstToken /= count
+	summary.Rated = len(rates)
+	if len(rates) > 0 {
+		summary.Mean /= float64(len(rates))
+		slices.Sort(rates)
+		summary.P50 = percentile(rates, 50)
+		summary.P95 = percentile(rates, 95)
+	}
+	return summary
+}
+
+// percentile returns the nearest rank percentile of sorted values
+func percentile(sorted []float64, p int) float64 {
+	rank := (p*len(sorted) + 99) / 100 // rounded up
+	return sorted[max(rank, 1)-1]
+}
+
+func writeBenchTable(context AppContext, summaries []BenchSummary) {
+	fmt.Fprintln(context.Output)
+	fmt.Fprintf(context.Output, "%-30s %5s %9s %9s %9s %10s %12s\n",
+		"MODEL", "RUNS", "MEAN", "P50", "P95", "LOAD", "FIRST TOKEN")
+	fmt.Fprintf(context.Output, "%-30s %5s %9s %9s %9s %10s %12s\n",
+		"-----", "----", "----", "---", "---", "----", "-----------")
+	unrated := 0
+	for _, s := range summaries {
+		unrated += s.Runs - s.Rated
+		if s.Runs == 0 {
+			reason := lib.WrapText(lib.Codes{lib.ESC_RED}, clip(s.Error, 60))
+			if s.Error == "" {
+				reason = "no runs finished"
+			}
+			fmt.Fprintf(context.Output, "%-30s %5d %s\n", s.Model, 0, reason)
+			continue
+		}
+		fmt.Fprintf(context.Output, "%-30s %5d %9s %9s %9s %8.0fms %10.0fms\n", s.Model, s.Runs,
+			rateText(s.Mean, s.Rated), rateText(s.P50, s.Rated), rateText(s.P95, s.Rated), s.Load, s.FirstToken)
+	}
+	fmt.Fprintln(context.Output, "MEAN, P50 and P95 are output tokens per second.")
+	if unrated > 0 {
+		fmt.Fprintf(context.Output, "%d run(s) came back without timings and are left out of the rates.\n", unrated)
+	}
+}
+
+// exportBench writes the summaries and runs to a JSON file. CSV has one kind of row to a file, so
+// the runs go to the file given and the summaries to one next to it ending in -summary.csv. The
+// files written are returned.
+func exportBench(path, format string, report BenchReport) ([]string, error) {
+	if format == "json" {
+		data, err := lib.PrettyJsonFromStruct(report, true)
+		if err != nil {
+			return nil, err
+		}
+		return []string{path}, os.WriteFile(path, append(data, '\n'), 0644)
+	}
+
+	runs := [][]string{{"model", "prompt", "run", "prompt_tokens", "output_tokens",
+		"prompt_tokens_per_second", "output_tokens_per_second", "load_ms", "first_token_ms", "wall_ms"}}
+	for _, run := range report.Runs {
+		runs = append(runs, []string{run.Model, strconv.Itoa(run.Prompt), strconv.Itoa(run.Run),
+			strconv.Itoa(run.PromptTokens), strconv.Itoa(run.OutputTokens), formatFloat(run.PromptRate),
+			formatFloat(run.OutputRate), formatFloat(run.Load), formatFloat(run.FirstToken),
+			formatFloat(run.Wall)})
+	}
+	summaries := [][]string{{"model", "runs", "rated_runs", "mean_tokens_per_second",
+		"p50_tokens_per_second", "p95_tokens_per_second", "mean_load_ms", "mean_first_token_ms", "error"}}
+	for _, s := range report.Summaries {
+		summaries = append(summaries, []string{s.Model, strconv.Itoa(s.Runs), strconv.Itoa(s.Rated),
+			formatFloat(s.Mean), formatFloat(s.P50), formatFloat(s.P95), formatFloat(s.Load),
+			formatFloat(s.FirstToken), s.Error})
+	}
+	summaryPath := strings.TrimSuffix(path, filepath.Ext(path)) + "-summary.csv"
+	if err := writeCSV(path, runs); err != nil {
+		return nil, err
+	}
+	if err := writeCSV(summaryPath, summaries); err != nil {
+		return nil, err
+	}
+	return []string{path, summaryPath}, nil
+}
+
+// writeCSV writes the rows to a new file, the error from closing it is returned as a full disk may
+// only show up then
+func writeCSV(path string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := csv.NewWriter(file).WriteAll(rows); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// rateText shows a rate, or a dash when no runs had timings
+func rateText(rate float64, rated int) string {
+	if rated == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", rate)
+}
+
+// perSecond returns tokens per second, zero when no time was taken
+func perSecond(count int, duration int64) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	return float64(count) / time.Duration(duration).Seconds()
+}
+
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
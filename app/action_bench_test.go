@@ -0,0 +1,88 @@
+// **********************************************************************************************100
+/*
+Tests for the benchmark summary: percentiles, means which leave out runs without timings, and the
+CSV files written by -export.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []float64
+		p      int
+		want   float64
+	}{
+		{"one value", []float64{7}, 50, 7},
+		{"one value p95", []float64{7}, 95, 7},
+		{"median of odd", []float64{1, 2, 3}, 50, 2},
+		{"median of even is the lower", []float64{1, 2, 3, 4}, 50, 2},
+		{"p95 of few is the top", []float64{1, 2, 3, 4}, 95, 4},
+		{"p95 of twenty", []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}, 95, 19},
+		{"p0 is the lowest", []float64{3, 5, 9}, 0, 3},
+		{"p100 is the highest", []float64{3, 5, 9}, 100, 9},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := percentile(test.sorted, test.p); got != test.want {
+				t.Errorf("percentile(%v, %d) = %v, want %v", test.sorted, test.p, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeRuns(t *testing.T) {
+	run := func(rate, load, first float64) BenchRun {
+		return BenchRun{Model: "m", OutputRate: rate, Load: load, FirstToken: first}
+	}
+	tests := []struct {
+		name string
+		runs []BenchRun
+		want BenchSummary
+	}{
+		{"no runs", nil, BenchSummary{Model: "m"}},
+		{"one run", []BenchRun{run(40, 100, 20)},
+			BenchSummary{Model: "m", Runs: 1, Rated: 1, Mean: 40, P50: 40, P95: 40, Load: 100, FirstToken: 20}},
+		{"rates are sorted", []BenchRun{run(30, 0, 0), run(10, 0, 0), run(20, 0, 0)},
+			BenchSummary{Model: "m", Runs: 3, Rated: 3, Mean: 20, P50: 20, P95: 30}},
+		{"untimed runs left out of the rates", []BenchRun{run(10, 100, 10), run(0, 300, 30), run(30, 200, 20)},
+			BenchSummary{Model: "m", Runs: 3, Rated: 2, Mean: 20, P50: 10, P95: 30, Load: 200, FirstToken: 20}},
+		{"no timed runs", []BenchRun{run(0, 100, 10), run(0, 300, 30)},
+			BenchSummary{Model: "m", Runs: 2, Load: 200, FirstToken: 20}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := summarizeRuns(BenchSummary{Model: "m"}, test.runs)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("summarizeRuns(%v) = %+v, want %+v", test.runs, got, test.want)
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.csv")
+	if err := writeCSV(path, [][]string{{"model", "note"}, {"llama3", "a, \"b\""}}); err != nil {
+		t.Fatalf("writeCSV failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "model,note\nllama3,\"a, \"\"b\"\"\"\n"; string(data) != want {
+		t.Errorf("writeCSV wrote %q, want %q", data, want)
+	}
+
+	if err := writeCSV(filepath.Join(t.TempDir(), "missing", "runs.csv"), nil); err == nil {
+		t.Error("writeCSV into a missing directory worked, want an error")
+	}
+}
@@ -0,0 +1,141 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Command to list the code blocks in the last answer and save, pipe or copy them.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/jceaser/ollama-query/lib"
+)
+
+// LastAnswer keeps the text of the most recent generate or chat answer
+type LastAnswer struct {
+	Model string
+	Text  string
+}
+
+// Set replaces the last answer, empty answers, like those which only call tools, are skipped
+func (a *LastAnswer) Set(model, text string) {
+	if a != nil && strings.TrimSpace(text) != "" {
+		a.Model, a.Text = model, text
+	}
+}
+
+// Blocks lists the code blocks in the last answer, or saves, pipes or copies one of them
+func Blocks(context AppContext, args ...string) (map[string]string, error) {
+	usage := "Usage: blocks [list] | save <n> <path> | pipe <n> <command> | copy <n>"
+	if context.Last == nil || context.Last.Text == "" {
+		return nil, fmt.Errorf("there is no answer yet, use generate or chat first")
+	}
+	blocks := lib.CodeBlocks(context.Last.Text)
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("the last answer has no code blocks")
+	}
+
+	subCommand := "list"
+	if len(args) > 0 {
+		subCommand = args[0]
+	}
+	if subCommand == "list" {
+		listBlocks(context, blocks)
+		return nil, nil
+	}
+	if len(args) < 2 {
+		return nil, fmt.Errorf("no block number given. %s", usage)
+	}
+	number, err := strconv.Atoi(args[1])
+	if err != nil || number < 1 || number > len(blocks) {
+		return nil, fmt.Errorf("no block [%s], pick 1 to %d", args[1], len(blocks))
+	}
+	block := blocks[number-1]
+
+	switch subCommand {
+	case "save":
+		if len(args) < 3 {
+			return nil, fmt.Errorf("no path given. %s", usage)
+		}
+		return nil, saveBlock(context, block, args[2])
+	case "pipe":
+		if len(args) < 3 {
+			return nil, fmt.Errorf("no command given. %s", usage)
+		}
+		command := exec.CommandContext(context.Ctx(), "sh", "-c", strings.Join(args[2:], " "))
+		command.Stdin = strings.NewReader(block.Code)
+		command.Stdout, command.Stderr = context.Output, context.Error
+		return nil, command.Run()
+	case "copy":
+		// the clipboard is set by the terminal, anywhere else the sequence would just be noise
+		if context.Structured() || !isTerminal(context.Output) {
+			return nil, fmt.Errorf("copy only works when the output is a terminal, use save or pipe instead. %s", usage)
+		}
+		fmt.Fprint(context.Output, clipboardSequence(block.Code))
+		fmt.Fprintf(context.Output, "Copied block %d, %d line(s), to the clipboard.\n", number,
+			strings.Count(block.Code, "\n"))
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown blocks command [%s]. %s", subCommand, usage)
+}
+
+// listBlocks shows each block with its number, language and first line
+func listBlocks(context AppContext, blocks []lib.CodeBlock) {
+	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+	fmt.Fprintf(context.Output, "Code blocks from the last answer by %s:\n", context.Last.Model)
+	for i, block := range blocks {
+		language := block.Language
+		if language == "" {
+			language = "text"
+		}
+		first, _, _ := strings.Cut(strings.TrimSpace(block.Code), "\n")
+		fmt.Fprintf(context.Output, "%3d %-12s %4d line(s)  %s\n", i+1, language,
+			strings.Count(block.Code, "\n"), clip(first, 50))
+	}
+}
+
+// saveBlock writes a block to a file, asking before replacing one
+func saveBlock(context AppContext, block lib.CodeBlock, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		replace, err := context.Confirm(fmt.Sprintf("%s already exists, replace it?", path))
+		if err != nil {
+			return err
+		}
+		if !replace {
+			fmt.Fprintln(context.Output, "Not saved.")
+			return nil
+		}
+	}
+	if err := os.WriteFile(path, []byte(block.Code), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(context.Output, "Saved %d line(s) to %s.\n", strings.Count(block.Code, "\n"), path)
+	return nil
+}
+
+// isTerminal is true when the file is a terminal rather than a pipe or a regular file
+func isTerminal(file *os.File) bool {
+	if file == nil {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// clipboardSequence returns the OSC 52 escape which asks the terminal to put text on the clipboard,
+// wrapped so tmux passes it on
+func clipboardSequence(text string) string {
+	sequence := "\033]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if os.Getenv("TMUX") != "" {
+		sequence = "\033Ptmux;" + strings.ReplaceAll(sequence, "\033", "\033\033") + "\033\\"
+	}
+	return sequence
+}
@@ -9,12 +9,11 @@ Created by Thomas.Cherry.gmail.com
 package app
 
 import (
-	"bufio"
-	"bytes"
+	"encoding/json"
 	"fmt"
-	"net/http"
 	"strings"
 
+	"github.com/jceaser/ollama-query/client"
 	"github.com/jceaser/ollama-query/lib"
 )
 
@@ -63,78 +62,172 @@ Final response:
 }
 */
 
-type ChatResponse struct {
-	Model     string  `json:"model"`
-	CreatedAt string  `json:"created_at"`
-	Message   Message `json:"message"`
-	Done      bool    `json:"done"`
-
-	// omit empty fields when unmarshaling
-	Context            []int `json:",omitempty"`
-	TotalDuration      int64 `json:",omitempty"`
-	LoadDuration       int64 `json:",omitempty"`
-	PromptEvalCount    int   `json:",omitempty"`
-	PromptEvalDuration int64 `json:",omitempty"`
-	EvalCount          int   `json:",omitempty"`
-	EvalDuration       int64 `json:",omitempty"`
-}
-
-type Message struct {
-	Role    string   `json:"role"`
-	Content string   `json:"content"`
-	Images  []string `json:"images,omitempty"`
-}
-
+// Chat sends a message along with the history of the current conversation. The model can be left
+// off when the conversation already has one, which makes "chat user hi" continue the conversation,
+// or when the profile gives a default model.
+// When tools are registered, any the model calls are run and the results sent back until the
+// model gives a final answer.
 func Chat(context AppContext, args ...string) (map[string]string, error) {
-	if len(args) < 3 {
-		return nil, fmt.Errorf("not enough arguments provided. Usage: chat <model> <role> <message>")
+	flags := context.Flags("chat")
+	formatFlag := flags.String("format", "", "json, or a JSON schema file the answer must follow")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	args = flags.Args()
+	format, err := parseFormat(*formatFlag)
+	if err != nil {
+		return nil, err
 	}
 
-	modelName := args[0]
-	prompt := []Message{{
-		Role:    args[1],
-		Content: strings.Join(args[2:], " "),
-	}}
-
-	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
-	fmt.Fprintf(context.Output, "Sending a chat message\n")
-
-	requestBody := map[string]interface{}{
-		"model":    modelName,
-		"messages": prompt,
+	conversation := context.Conversation
+	if conversation == nil {
+		conversation = &Conversation{}
+	}
+	model := conversation.Model
+	if model == "" {
+		model = context.Model
+	}
+	if len(args) >= 2 && IsRole(args[0]) && model != "" {
+		args = append([]string{model}, args...)
+	}
+	if len(args) < 3 {
+		return nil, fmt.Errorf("not enough arguments provided. Usage: chat [-format json|<schema file>] [model] <role> <message>")
 	}
 
-	jsonData, err := lib.JsonFromStruct(requestBody)
+	prompt, err := preparePrompt(context, args[0], args[2:])
 	if err != nil {
 		return nil, err
 	}
-	jsonBytes := bytes.NewBuffer(jsonData)
 
-	resp, err := http.Post(context.HostName+"/api/chat", "application/json", jsonBytes)
-	if err != nil {
-		resp.Body.Close()
-		return nil, err
+	conversation.Model = args[0]
+	start := len(conversation.Messages)
+	if len(conversation.Messages) == 0 && context.System != "" && args[1] != "system" {
+		conversation.Add(Message{Role: "system", Content: context.System})
+	}
+	conversation.Add(Message{
+		Role:    args[1],
+		Content: prompt.Text,
+		Images:  prompt.Images,
+	})
+	request := &client.ChatRequest{
+		Model: conversation.Model,
+		Tools: context.Tools.Definitions(),
+
+		Options:   context.Options.Model(),
+		KeepAlive: context.Options.KeepAlive(),
+	}
+	if format != nil {
+		request.Format = format.Raw
+	}
+
+	structured := context.Structured()
+	if !structured {
+		fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+		fmt.Fprintf(context.Output, "Sending a chat message\n")
 	}
-	defer resp.Body.Close()
 
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		response, err := lib.StructFromJson[ChatResponse]([]byte(line))
+	var final ChatResponse
+	stats := startStats()
+	for round := 0; ; round++ {
+		request.Messages = conversation.Messages
+		reply, response, err := streamChat(context, request, stats, format == nil)
+		if err != nil && context.Ctx().Err() != nil && reply.Content != "" {
+			context.Attachments.Clear() // the images stay in the conversation with the message
+			return nil, interruptChat(context, conversation, reply, format != nil)
+		}
 		if err != nil {
-			lib.Log.Warn.Printf("Error unmarshaling response line: %v\n", err)
-			continue
+			// forget the messages which were never answered so they are not sent again
+			conversation.Messages = conversation.Messages[:start]
+			if context.Ctx().Err() != nil {
+				return nil, ErrInterrupted
+			}
+			return nil, err
 		}
-		fmt.Fprintf(context.Output, "%s", response.Message.Content)
-		if response.Done {
-			fmt.Fprintln(context.Output, "\nChat complete.")
-			fmt.Fprintf(context.Output, "Stats:\n%v\n", response)
+		final = response
+		context.Attachments.Clear()
+		conversation.Add(reply)
+		if len(reply.ToolCalls) == 0 {
 			break
 		}
+		if round >= maxToolRounds {
+			// the calls in the last reply will never be answered, so it can not be sent again
+			conversation.Messages = conversation.Messages[:len(conversation.Messages)-1]
+			return nil, fmt.Errorf("no final answer after %d rounds of tool calls", round+1)
+		}
+		runToolCalls(context, reply.ToolCalls, conversation)
 	}
+	defer context.Stats.record(context, stats) // the footer goes after everything else is shown
 
-	if err := scanner.Err(); err != nil {
-		lib.Log.Error.Printf("%v\n", err.Error())
+	if format != nil {
+		if err := format.check(context, conversation.Messages[len(conversation.Messages)-1].Content); err != nil {
+			return nil, err
+		}
+	}
+	if structured {
+		final.Message = conversation.Messages[len(conversation.Messages)-1]
+		return nil, writeDocument(context, final)
 	}
+	fmt.Fprintln(context.Output, "Chat complete.")
 	return nil, nil
 }
+
+// interruptChat keeps the part of the answer which arrived before Ctrl-C in the conversation,
+// marked so it is clear the answer was cut short, and shows it if it was not already streamed
+func interruptChat(context AppContext, conversation *Conversation, reply Message, hidden bool) error {
+	reply.ToolCalls = nil // tools asked for in an unfinished answer are never run
+	reply.Content += interruptedMark
+	conversation.Add(reply)
+	if context.Structured() {
+		writeDocument(context, ChatResponse{Model: conversation.Model, Message: reply, DoneReason: interruptedReason})
+	} else if hidden {
+		fmt.Fprint(context.Output, reply.Content)
+	}
+	return ErrInterrupted
+}
+
+// streamChat sends one request, printing the answer as it arrives when show is set, and returns the
+// whole reply along with the final response. The timings and token counts are added to stats.
+func streamChat(context AppContext, request *client.ChatRequest, stats *RequestStats, show bool) (Message, ChatResponse, error) {
+	reply := Message{Role: "assistant"}
+	var answer strings.Builder
+	var final ChatResponse
+	show = show && !context.Structured()
+	printer := newAnswerPrinter(context, nil)
+	err := context.Api().Chat(context.Ctx(), request, func(response ChatResponse) error {
+		if response.Message.Content != "" || len(response.Message.ToolCalls) > 0 {
+			stats.Token()
+		}
+		answer.WriteString(response.Message.Content)
+		reply.ToolCalls = append(reply.ToolCalls, response.Message.ToolCalls...)
+		if show {
+			printer.Print(response.Message.Content)
+		}
+		if response.Done {
+			final = response
+			stats.Finish(response.Metrics)
+		}
+		return nil
+	})
+	if show {
+		printer.Done()
+	}
+	reply.Content = answer.String()
+	context.Last.Set(request.Model, reply.Content)
+	return reply, final, err
+}
+
+// runToolCalls runs each tool the model asked for, adding the results to the conversation
+func runToolCalls(context AppContext, calls []client.ToolCall, conversation *Conversation) {
+	for _, call := range calls {
+		arguments, _ := json.Marshal(call.Function.Arguments)
+		result, err := context.Tools.Call(context.Ctx(), call)
+		if err != nil {
+			lib.Log.Warn.Printf("Tool %s failed: %v\n", call.Function.Name, err)
+		}
+		if !context.Structured() {
+			fmt.Fprintln(context.Output, lib.WrapText(lib.Codes{lib.ESC_YELLOW},
+				fmt.Sprintf("\n-> %s(%s) = %s", call.Function.Name, arguments, clip(result.Content, 60))))
+		}
+		conversation.Add(result)
+	}
+}
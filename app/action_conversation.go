@@ -0,0 +1,82 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Commands to manage the chat history: start over, show it, undo the last turn or change the model.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jceaser/ollama-query/lib"
+)
+
+func ConversationCommand(context AppContext, args ...string) (map[string]string, error) {
+	if context.Conversation == nil {
+		return nil, fmt.Errorf("no conversation available")
+	}
+	conversation := context.Conversation
+
+	subCommand := "show"
+	if len(args) > 0 {
+		subCommand = args[0]
+	}
+
+	switch subCommand {
+	case "new":
+		model := ""
+		if len(args) > 1 {
+			model = args[1]
+		}
+		conversation.Reset(model)
+		fmt.Fprintf(context.Output, "Started a new conversation with %s.\n", modelOrNone(conversation.Model))
+	case "show":
+		printTranscript(context, conversation)
+	case "undo":
+		removed := conversation.DropLastTurn()
+		fmt.Fprintf(context.Output, "Removed %d message(s), %d left.\n", removed, len(conversation.Messages))
+	case "model":
+		if len(args) < 2 {
+			fmt.Fprintf(context.Output, "Current model: %s\n", modelOrNone(conversation.Model))
+			return nil, nil
+		}
+		conversation.Model = args[1]
+		fmt.Fprintf(context.Output, "Conversation will continue with %s, keeping %d message(s).\n",
+			conversation.Model, len(conversation.Messages))
+	default:
+		return nil, fmt.Errorf("unknown conversation command [%s]. Usage: conversation new|show|undo|model [name]",
+			subCommand)
+	}
+	return nil, nil
+}
+
+func modelOrNone(model string) string {
+	if model == "" {
+		return "no model"
+	}
+	return model
+}
+
+func printTranscript(context AppContext, conversation *Conversation) {
+	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+	fmt.Fprintf(context.Output, "Conversation with %s, %d message(s):\n",
+		modelOrNone(conversation.Model), len(conversation.Messages))
+	for _, message := range conversation.Messages {
+		color := lib.Codes{lib.ESC_BOLD, lib.ESC_BLUE}
+		if message.Role == "assistant" {
+			color = lib.Codes{lib.ESC_BOLD, lib.ESC_GREEN}
+		}
+		fmt.Fprintf(context.Output, "%s: %s\n", lib.WrapText(color, fmt.Sprintf("%9s", message.Role)),
+			message.Content)
+		for _, call := range message.ToolCalls {
+			arguments, _ := json.Marshal(call.Function.Arguments)
+			fmt.Fprintf(context.Output, "%9s  -> %s(%s)\n", "", call.Function.Name, arguments)
+		}
+	}
+	fmt.Fprintln(context.Output)
+}
@@ -0,0 +1,32 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Code to issue /api/copy requests to give a model a new name.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jceaser/ollama-query/client"
+)
+
+// curl http://localhost:11434/api/copy -d '{"source": "llama3.2", "destination": "llama3-backup"}'
+func CopyModel(context AppContext, args ...string) (map[string]string, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("not enough arguments provided. Usage: cp <source> <destination>")
+	}
+
+	request := &client.CopyRequest{Source: args[0], Destination: args[1]}
+	if err := context.Api().Copy(context.Ctx(), request); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+	fmt.Fprintf(context.Output, "Copied %s to %s.\n", request.Source, request.Destination)
+	return nil, nil
+}
@@ -0,0 +1,310 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Code to issue /api/create requests from a Modelfile, uploading any local files it names first.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jceaser/ollama-query/client"
+)
+
+// localFile is a file named in a Modelfile which has to be uploaded as a blob before creating
+type localFile struct {
+	path    string
+	adapter bool // true for ADAPTER, false for FROM
+}
+
+/*
+A Modelfile looks like:
+
+	# comment
+	FROM llama3.2
+	PARAMETER temperature 1
+	PARAMETER stop "<|eot_id|>"
+	SYSTEM """
+	You are Mario from Super Mario Bros.
+	"""
+	MESSAGE user Is Toronto in Canada?
+
+curl http://localhost:11434/api/create -d '{"model": "mario", "from": "llama3.2", "system": "..."}'
+streams:
+
+	{"status":"reading model metadata"}
+	{"status":"creating system layer"}
+	{"status":"writing manifest"}
+	{"status":"success"}
+*/
+func CreateModel(context AppContext, args ...string) (map[string]string, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("not enough arguments provided. Usage: create <name> <Modelfile path>")
+	}
+	model, path := args[0], args[1]
+
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	request, files, err := parseModelfile(string(text), filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	request.Model = model
+
+	ctx := context.Ctx()
+
+	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+	fmt.Fprintf(context.Output, "Creating %s from %s, press Ctrl-C to cancel.\n", model, path)
+
+	start := time.Now()
+	for _, file := range files {
+		digest, err := uploadBlob(context, ctx, file.path)
+		if err != nil {
+			return nil, reportTransfer(context, ctx, "create", model, "", start, err)
+		}
+		if file.adapter {
+			if request.Adapters == nil {
+				request.Adapters = map[string]string{}
+			}
+			request.Adapters[filepath.Base(file.path)] = digest
+		} else {
+			if request.Files == nil {
+				request.Files = map[string]string{}
+			}
+			request.Files[filepath.Base(file.path)] = digest
+		}
+	}
+
+	printer := newProgressPrinter(context.Output)
+	status := ""
+	err = context.Api().Create(ctx, request, func(progress client.ProgressResponse) error {
+		printer.Update(progress)
+		status = progress.Status
+		return nil
+	})
+	printer.Done()
+	return nil, reportTransfer(context, ctx, "create", model, status, start, err)
+}
+
+/**************************************/
+// MARK: - Blobs
+
+// uploadBlob sends a file to the server unless it already has it, returning the digest
+func uploadBlob(context AppContext, ctx context.Context, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	digest := fmt.Sprintf("sha256:%x", hash.Sum(nil))
+
+	api := context.Api()
+	exists, err := api.HasBlob(ctx, digest)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		fmt.Fprintf(context.Output, "Server already has %s.\n", filepath.Base(path))
+		return digest, nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(context.Output, "Uploading %s...\n", filepath.Base(path))
+	return digest, api.CreateBlob(ctx, digest, file)
+}
+
+/**************************************/
+// MARK: - Modelfile
+
+// parseModelfile turns the instructions of a Modelfile into a create request. Paths are relative to
+// dir, and local files named by FROM or ADAPTER are returned so they can be uploaded first.
+func parseModelfile(text, dir string) (*client.CreateRequest, []localFile, error) {
+	request := &client.CreateRequest{}
+	files := []localFile{}
+
+	rest := text
+	for lineNumber, nextLine := 0, 1; rest != ""; {
+		var line string
+		line, rest = cutLine(rest)
+		lineNumber = nextLine
+		nextLine++
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		instruction, value, _ := strings.Cut(line, " ")
+		instruction = strings.ToUpper(instruction)
+		value = strings.TrimSpace(value)
+
+		// arguments may be in triple quotes and run over several lines
+		var err error
+		before := rest
+		value, rest, err = readModelfileValue(value, rest)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		nextLine += strings.Count(before[:len(before)-len(rest)], "\n")
+
+		switch instruction {
+		case "FROM", "ADAPTER":
+			path, isDir, err := modelfilePath(instruction, value, dir)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			switch {
+			case path == "":
+				request.From = value
+			case isDir:
+				entries, err := os.ReadDir(path)
+				if err != nil {
+					return nil, nil, err
+				}
+				for _, entry := range entries {
+					if entry.Type().IsRegular() {
+						files = append(files, localFile{path: filepath.Join(path, entry.Name())})
+					}
+				}
+			default:
+				files = append(files, localFile{path: path, adapter: instruction == "ADAPTER"})
+			}
+		case "PARAMETER":
+			name, parameter, _ := strings.Cut(value, " ")
+			parameter, _, err = readModelfileValue(strings.TrimSpace(parameter), "")
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			if request.Parameters == nil {
+				request.Parameters = map[string]any{}
+			}
+			if name == "stop" {
+				stops, _ := request.Parameters["stop"].([]string)
+				request.Parameters["stop"] = append(stops, parameter)
+			} else {
+				request.Parameters[name] = parameterValue(parameter)
+			}
+		case "TEMPLATE":
+			request.Template = value
+		case "SYSTEM":
+			request.System = value
+		case "LICENSE":
+			request.License = append(request.License, value)
+		case "MESSAGE":
+			role, content, _ := strings.Cut(value, " ")
+			if !IsRole(role) {
+				return nil, nil, fmt.Errorf("line %d: unknown message role [%s]", lineNumber, role)
+			}
+			content, _, err = readModelfileValue(strings.TrimSpace(content), "")
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			request.Messages = append(request.Messages, Message{Role: role, Content: content})
+		default:
+			return nil, nil, fmt.Errorf("line %d: unknown instruction [%s]", lineNumber, instruction)
+		}
+	}
+
+	if request.From == "" && len(files) == 0 {
+		return nil, nil, fmt.Errorf("the Modelfile has no FROM instruction")
+	}
+	return request, files, nil
+}
+
+func cutLine(text string) (string, string) {
+	line, rest, _ := strings.Cut(text, "\n")
+	return line, rest
+}
+
+// readModelfileValue removes quotes from value. A value starting with """ continues until the
+// closing """, which may be further on in rest.
+func readModelfileValue(value, rest string) (string, string, error) {
+	switch {
+	case strings.HasPrefix(value, `"""`):
+		body := value[3:]
+		if end := strings.Index(body, `"""`); end >= 0 {
+			return body[:end], rest, nil
+		}
+		end := strings.Index(rest, `"""`)
+		if end < 0 {
+			return "", "", fmt.Errorf(`no closing """ found`)
+		}
+		body = body + "\n" + rest[:end]
+		_, rest = cutLine(rest[end:])
+		return strings.Trim(body, "\n"), rest, nil
+	case strings.HasPrefix(value, `"`):
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return "", "", fmt.Errorf("bad quoted value %s", value)
+		}
+		return unquoted, rest, nil
+	}
+	return value, rest, nil
+}
+
+// modelfilePath resolves the value of a FROM or ADAPTER against dir, returning the path and if it is
+// a directory. An ADAPTER is always a file. A FROM is a model name, and an empty path is returned,
+// unless it looks like a path: starting with ".", "/" or "~", or with a separator in it. As model
+// names like me/model have a separator too, those are only paths when they are on disk.
+func modelfilePath(instruction, value, dir string) (string, bool, error) {
+	explicit := strings.HasPrefix(value, ".") || strings.HasPrefix(value, "/") || strings.HasPrefix(value, "~")
+	hasSeparator := strings.ContainsRune(value, '/') || strings.ContainsRune(value, filepath.Separator)
+	if instruction == "FROM" && !explicit && !hasSeparator {
+		return "", false, nil
+	}
+
+	path := value
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	info, err := os.Stat(path)
+	switch {
+	case err != nil && instruction == "FROM" && !explicit:
+		return "", false, nil
+	case err != nil && instruction == "ADAPTER":
+		return "", false, fmt.Errorf("adapter %s not found", value)
+	case err != nil:
+		return "", false, fmt.Errorf("file %s not found", value)
+	case info.IsDir() && instruction == "ADAPTER":
+		return "", false, fmt.Errorf("adapter %s is a directory, not a file", value)
+	}
+	return path, info.IsDir(), nil
+}
+
+// parameterValue converts a PARAMETER value to the number or boolean it looks like
+func parameterValue(value string) any {
+	if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return number
+	}
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return number
+	}
+	if flag, err := strconv.ParseBool(value); err == nil {
+		return flag
+	}
+	return value
+}
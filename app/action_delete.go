@@ -0,0 +1,47 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Code to issue /api/delete requests to remove a model.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jceaser/ollama-query/client"
+)
+
+// curl -X DELETE http://localhost:11434/api/delete -d '{"model": "llama3:13b"}'
+func DeleteModel(context AppContext, args ...string) (map[string]string, error) {
+	flags := context.Flags("rm")
+	force := flags.Bool("force", false, "delete without asking first")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() < 1 {
+		return nil, fmt.Errorf("no model name provided. Usage: rm [-force] <model>")
+	}
+	model := flags.Arg(0)
+
+	if !*force {
+		okay, err := context.Confirm(fmt.Sprintf("Delete model %s?", model))
+		if err != nil {
+			return nil, fmt.Errorf("%v, use -force to delete without asking", err)
+		}
+		if !okay {
+			fmt.Fprintf(context.Output, "Model %s was not deleted.\n", model)
+			return nil, nil
+		}
+	}
+
+	if err := context.Api().Delete(context.Ctx(), &client.DeleteRequest{Model: model}); err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+	fmt.Fprintf(context.Output, "Deleted %s.\n", model)
+	return nil, nil
+}
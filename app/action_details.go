@@ -9,66 +9,12 @@ Created by Thomas.Cherry.gmail.com
 package app
 
 import (
-	"bytes"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 
-	"github.com/jceaser/ollama-query/lib"
+	"github.com/jceaser/ollama-query/client"
 )
 
-// ... existing code ...
-
-type ModelName struct {
-	Model string `json:"model"`
-}
-
-type Modelfile struct {
-	Details struct {
-		Family            string   `json:"family"`
-		Families          []string `json:"families"`
-		Format            string   `json:"format"`
-		ParentModel       string   `json:"parent_model"`
-		ParameterSize     string   `json:"parameter_size"`
-		QuantizationLevel string   `json:"quantization_level"`
-	} `json:"details"`
-
-	ModelInfo struct {
-		GGML struct {
-			BosTokenID    int      `json:"bos_token_id"`
-			EOSTokenID    int      `json:"eos_token_id"`
-			Merges        []string `json:"merges"`
-			Pre           string   `json:"pre"`
-			TokenType     []string `json:"token_type"`
-			Tokens        []string `json:"tokens"`
-			Model         string   `json:"model"`
-			TokenizerType string   `json:"tokenizer.ggml.token_type"` // fix json path here
-		} `json:"tokenizer.ggml"`
-
-		Llama struct {
-			Architecture       string `json:"llama.attention.head_count_kv"`
-			ContextLength      int    `json:"llama.context_length"`
-			BlockCount         int    `json:"llama.block_count"`
-			FeedForwardLength  int    `json:"llama.feed_forward_length"`
-			AttentionHeadCount int    `json:"llama.attention.head_count"`
-			RopeDimensionCount int    `json:"llama.rope.dimension_count"`
-			VocabSize          int    `json:"llama.vocab_size"`
-			EmbeddingLength    int    `json:"llama.embedding_length"`
-			FreqBase           int    `json:"llama.rope.freq_base"`
-		} `json:"llama"`
-
-		General struct {
-			FileType            int    `json:"general.file_type"`
-			ParameterCount      uint64 `json:"general.parameter_count"`
-			QuantizationVersion int    `json:"general.quantization_version"`
-			Architecture        string `json:"general.architecture"`
-		} `json:"general"`
-
-		Capabilities []string `json:"capabilities"`
-	} `json:"model_info"`
-}
-
 /*
 	curl http://localhost:11434/api/show -d '{
 	  "model": "llava"
@@ -115,36 +61,22 @@ returns:
 	}
 */
 func ShowModelDetails(context AppContext, params ...string) (map[string]string, error) {
-	if len(params) < 1 {
-		return nil, fmt.Errorf("no model name provided")
-	}
-
-	nameOfModel := params[0]
-	modelName := ModelName{Model: nameOfModel}
-	jsonBytes, err := lib.JsonFromStruct(modelName)
-	if err != nil {
-		return nil, err
+	nameOfModel := context.Model
+	if len(params) > 0 {
+		nameOfModel = params[0]
 	}
-
-	/*resp, err := http.Post(context.HostName+"/api/show",
-	"application/json",
-	io.NopCloser(bytes.NewReader(jsonBytes)))*/
-
-	resp, err := http.Post(context.HostName+"/api/show", "application/json", bytes.NewReader(jsonBytes))
-	if err != nil {
-		resp.Body.Close()
-		return nil, err
+	if nameOfModel == "" {
+		return nil, fmt.Errorf("no model name provided. Usage: show [model]")
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	modelDetails, err := context.Api().Show(context.Ctx(), &client.ShowRequest{Model: nameOfModel})
 	if err != nil {
 		return nil, err
 	}
-
-	modelDetails, err := lib.StructFromJson[Modelfile](body)
-	if err != nil {
-		return nil, err
+	if context.Structured() {
+		return nil, writeDocument(context, struct {
+			Model string `json:"model"`
+			*Modelfile
+		}{nameOfModel, modelDetails})
 	}
 
 	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
@@ -154,5 +86,8 @@ func ShowModelDetails(context AppContext, params ...string) (map[string]string,
 	fmt.Fprintf(context.Output, "Family: %s\n", modelDetails.Details.Family)
 	fmt.Fprintf(context.Output, "Parameter Size: %s\n", modelDetails.Details.ParameterSize)
 	fmt.Fprintf(context.Output, "Quantization Level: %s\n", modelDetails.Details.QuantizationLevel)
+	if len(modelDetails.Capabilities) > 0 {
+		fmt.Fprintf(context.Output, "Capabilities: %s\n", strings.Join(modelDetails.Capabilities, ", "))
+	}
 	return nil, nil
 }
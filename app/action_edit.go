@@ -0,0 +1,81 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Command to write a chat message in an external editor.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Edit opens $VISUAL or $EDITOR, falling back to vi, on an empty file and sends what is written as a
+// user message in the current conversation. Nothing is sent if the file is left empty.
+func Edit(context AppContext, args ...string) (map[string]string, error) {
+	model := ""
+	if context.Conversation != nil {
+		model = context.Conversation.Model
+	}
+	if model == "" {
+		model = context.Model
+	}
+	if len(args) > 0 {
+		model = args[0]
+	}
+	if model == "" {
+		return nil, fmt.Errorf("no model in the conversation. Usage: edit [model]")
+	}
+
+	text, err := editText("")
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(text) == "" {
+		fmt.Fprintln(context.Output, "Nothing to send.")
+		return nil, nil
+	}
+	return Chat(context, model, "user", strings.TrimSpace(text))
+}
+
+// editText lets the user change text in their editor, returning the saved result
+func editText(text string) (string, error) {
+	file, err := os.CreateTemp("", "ollama-query-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// the editor may be given with arguments, like "code --wait"
+	words := strings.Fields(editor)
+	command := exec.Command(words[0], append(words[1:], file.Name())...)
+	command.Stdin, command.Stdout, command.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := command.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %v", editor, err)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,208 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Code to issue /api/embed requests, print the vectors, and compare two inputs.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jceaser/ollama-query/client"
+	"github.com/jceaser/ollama-query/lib"
+)
+
+/*
+curl http://localhost:11434/api/embed -d '{"model": "all-minilm", "input": ["Why is the sky blue?"]}'
+
+returns:
+
+	{
+	  "model": "all-minilm",
+	  "embeddings": [[0.010071029, -0.0017594862, 0.05007221, ...]],
+	  "total_duration": 14143917,
+	  "load_duration": 1019500,
+	  "prompt_eval_count": 8
+	}
+*/
+func Embed(context AppContext, args ...string) (map[string]string, error) {
+	if len(args) > 0 && args[0] == "similarity" {
+		return embedSimilarity(context, args[1:]...)
+	}
+
+	usage := "Usage: embed [-format json|csv|summary] [-n count] [-file path] [-model name] [model] [text]"
+	flags := context.Flags("embed")
+	format := flags.String("format", "summary", "output as json, csv or summary")
+	count := flags.Int("n", 5, "number of values to show in the summary")
+	file := flags.String("file", "", "embed each line of a file instead of the text")
+	modelFlag := flags.String("model", "", "model to use instead of the first word or the profile's")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	model, text := context.modelArg(*modelFlag, flags.Args())
+	if model == "" {
+		return nil, fmt.Errorf("no model name provided. %s", usage)
+	}
+
+	var inputs []string
+	if *file != "" {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				inputs = append(inputs, line)
+			}
+		}
+	} else if len(text) > 0 {
+		inputs = []string{strings.Join(text, " ")}
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("nothing to embed. %s", usage)
+	}
+
+	response, err := context.Api().Embed(context.Ctx(), &client.EmbedRequest{Model: model, Input: inputs})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("asked for %d embeddings but got %d", len(inputs), len(response.Embeddings))
+	}
+
+	switch *format {
+	case "json":
+		type embedding struct {
+			Input     string    `json:"input"`
+			Embedding []float64 `json:"embedding"`
+		}
+		document := struct {
+			Model      string      `json:"model"`
+			Embeddings []embedding `json:"embeddings"`
+		}{Model: response.Model}
+		for i, input := range inputs {
+			document.Embeddings = append(document.Embeddings, embedding{input, response.Embeddings[i]})
+		}
+		data, err := lib.PrettyJsonFromStruct(document, true)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintln(context.Output, string(data))
+	case "csv":
+		writer := csv.NewWriter(context.Output)
+		for i, input := range inputs {
+			row := []string{input}
+			for _, value := range response.Embeddings[i] {
+				row = append(row, strconv.FormatFloat(value, 'g', -1, 64))
+			}
+			writer.Write(row)
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return nil, err
+		}
+	case "summary":
+		fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+		fmt.Fprintf(context.Output, "Embeddings from %s:\n", response.Model)
+		for i, input := range inputs {
+			vector := response.Embeddings[i]
+			fmt.Fprintf(context.Output, "%3d %s\n    dimension: %d, norm: %.4f, values: %s\n",
+				i+1, clip(input, 70), len(vector), norm(vector), formatValues(vector, *count))
+		}
+	default:
+		return nil, fmt.Errorf("unknown format [%s]. %s", *format, usage)
+	}
+	return nil, nil
+}
+
+// embedSimilarity embeds two texts, separated by a "|", and prints their cosine similarity
+func embedSimilarity(context AppContext, args ...string) (map[string]string, error) {
+	usage := "Usage: embed similarity [-model name] [model] <first text> | <second text>"
+	flags := context.Flags("embed similarity")
+	modelFlag := flags.String("model", "", "model to use instead of the first word or the profile's")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	model, args := context.modelArg(*modelFlag, flags.Args())
+	if model == "" {
+		return nil, fmt.Errorf("no model name provided. %s", usage)
+	}
+	first, second, found := strings.Cut(strings.Join(args, " "), "|")
+	first, second = strings.TrimSpace(first), strings.TrimSpace(second)
+	if !found || first == "" || second == "" {
+		return nil, fmt.Errorf("two texts are needed. %s", usage)
+	}
+
+	request := &client.EmbedRequest{Model: model, Input: []string{first, second}}
+	response, err := context.Api().Embed(context.Ctx(), request)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Embeddings) != 2 {
+		return nil, fmt.Errorf("asked for 2 embeddings but got %d", len(response.Embeddings))
+	}
+	similarity, err := cosineSimilarity(response.Embeddings[0], response.Embeddings[1])
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+	fmt.Fprintf(context.Output, "1: %s\n2: %s\n", clip(first, 76), clip(second, 76))
+	fmt.Fprintf(context.Output, "Cosine similarity: %.4f\n", similarity)
+	return map[string]string{"similarity": strconv.FormatFloat(similarity, 'f', -1, 64)}, nil
+}
+
+/**************************************/
+// MARK: - Vector math
+
+func norm(vector []float64) float64 {
+	sum := 0.0
+	for _, value := range vector {
+		sum += value * value
+	}
+	return math.Sqrt(sum)
+}
+
+func cosineSimilarity(a, b []float64) (float64, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("vectors have different dimensions, %d and %d", len(a), len(b))
+	}
+	dot := 0.0
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	lengths := norm(a) * norm(b)
+	if lengths == 0 {
+		return 0, fmt.Errorf("can not compare a zero length vector")
+	}
+	return dot / lengths, nil
+}
+
+func formatValues(vector []float64, count int) string {
+	count = max(0, min(count, len(vector)))
+	values := make([]string, count)
+	for i := range count {
+		values[i] = strconv.FormatFloat(vector[i], 'f', 6, 64)
+	}
+	if count < len(vector) {
+		values = append(values, "...")
+	}
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+// clip shortens text to length runes for display
+func clip(text string, length int) string {
+	runes := []rune(strings.ReplaceAll(text, "\n", " "))
+	if len(runes) <= length {
+		return string(runes)
+	}
+	return string(runes[:length-3]) + "..."
+}
@@ -9,54 +9,14 @@ Created by Thomas.Cherry.gmail.com
 package app
 
 import (
-	"bufio"
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"net/http"
-	"reflect"
 	"strings"
 
+	"github.com/jceaser/ollama-query/client"
 	"github.com/jceaser/ollama-query/lib"
 )
 
-type ResponseFromJson struct {
-	Model     string `json:"model"`
-	CreatedAt string `json:"created_at"`
-	Response  string `json:"response"`
-	Done      bool   `json:"done"`
-
-	// omit empty fields when unmarshaling
-	Context            []int `json:",omitempty"` //this should be passed along with the generate request and will be returned in the response, but it is not always present in the response so we need to make it omitempty
-	TotalDuration      int64 `json:",omitempty"`
-	LoadDuration       int64 `json:",omitempty"`
-	PromptEvalCount    int   `json:",omitempty"`
-	PromptEvalDuration int64 `json:",omitempty"`
-	EvalCount          int   `json:",omitempty"`
-	EvalDuration       int64 `json:",omitempty"`
-}
-
-func (r ResponseFromJson) String2() string {
-	s := fmt.Sprintf(
-		"Model: %s\nCreated At: %s\nResponse: %s\nDone: %v\nContext: %v\nTotal Duration: %d\nLoad Duration: %d\nPrompt Eval Count: %d\nPrompt Eval Duration: %d\nEval Count: %d\nEval Duration: %d",
-		r.Model, r.CreatedAt, r.Response, r.Done, r.Context, r.TotalDuration, r.LoadDuration, r.PromptEvalCount, r.PromptEvalDuration, r.EvalCount, r.EvalDuration)
-	return s
-}
-
-func (r ResponseFromJson) String() string {
-	var sb strings.Builder
-	val := reflect.ValueOf(r)
-	typ := reflect.TypeOf(r)
-
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		value := val.Field(i)
-		sb.WriteString(fmt.Sprintf("%20s: %v\n", field.Name, value.Interface()))
-	}
-
-	return sb.String()
-}
-
 /*
 
 curl http://localhost:11434/api/generate -d '{
@@ -90,49 +50,61 @@ Last response looks like this:
 */
 
 func GenerateText(context AppContext, args ...string) (map[string]string, error) {
-	if len(args) < 2 {
-		return nil, fmt.Errorf("not enough arguments provided. Usage: generate <model_name> <prompt>")
-	}
-	modelName := args[0]
-	prompt := strings.Join(args[1:], " ")
-
-	// Create the request body
-	requestBody := map[string]interface{}{
-		"model":  modelName,
-		"prompt": prompt,
+	flags := context.Flags("generate")
+	formatFlag := flags.String("format", "", "json, or a JSON schema file the answer must follow")
+	modelFlag := flags.String("model", "", "model to use instead of the first word or the profile's")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
 	}
-	if len(context.Context) > 0 {
-		requestBody["context"] = context.Context
+	model, args := context.modelArg(*modelFlag, flags.Args())
+	if model == "" || len(args) < 1 {
+		return nil, fmt.Errorf("not enough arguments provided. Usage: generate [-format json|<schema file>] [-model name] [model] <prompt>")
 	}
-
-	// Convert the request body to JSON
-	jsonData, err := lib.JsonFromStruct(requestBody)
+	format, err := parseFormat(*formatFlag)
 	if err != nil {
 		return nil, err
 	}
-	jsonBytes := bytes.NewBuffer(jsonData)
 
-	// Send the POST request to the Ollama server
-	resp, err := http.Post(context.HostName+"/api/generate", "application/json", jsonBytes)
+	prompt, err := preparePrompt(context, model, args)
 	if err != nil {
-		resp.Body.Close()
 		return nil, err
 	}
-	defer resp.Body.Close()
+	request := &client.GenerateRequest{
+		Model:   model,
+		Prompt:  prompt.Text,
+		Images:  prompt.Images,
+		System:  context.System,
+		Context: context.Context,
+
+		Options:   context.Options.Model(),
+		KeepAlive: context.Options.KeepAlive(),
+	}
+	if format != nil {
+		request.Format = format.Raw
+	}
 
-	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+	structured := context.Structured()
+	if !structured {
+		fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+	}
 
-	scanner := bufio.NewScanner(resp.Body)
 	result := map[string]string{}
-	for scanner.Scan() {
-		line := scanner.Text()
-		response, err := lib.StructFromJson[ResponseFromJson]([]byte(line))
-		if err != nil {
-			lib.Log.Warn.Printf("Error parsing response line: %v\n", err)
-			continue
+	var answer strings.Builder
+	var final ResponseFromJson
+	show := !structured && format == nil
+	printer := newAnswerPrinter(context, lib.Codes{lib.ESC_GREEN})
+	stats := startStats()
+	err = context.Api().Generate(context.Ctx(), request, func(response ResponseFromJson) error {
+		if response.Response != "" {
+			stats.Token()
+		}
+		answer.WriteString(response.Response)
+		if show {
+			printer.Print(response.Response)
 		}
-		fmt.Fprintf(context.Output, lib.WrapText(lib.Codes{lib.ESC_GREEN}, "%s"), response.Response)
 		if response.Done {
+			final = response
+			stats.Finish(response.Metrics)
 			if len(response.Context) > 0 {
 				jsonData, err := json.Marshal(response.Context)
 				if err != nil {
@@ -144,13 +116,40 @@ func GenerateText(context AppContext, args ...string) (map[string]string, error)
 			if context.Verbose > 0 {
 				lib.Log.Debug.Printf("%v\n", response)
 			}
-			fmt.Fprintf(context.Output, "\n\n")
-			break
+		}
+		return nil
+	})
+	if show {
+		printer.Done()
+		if err == nil {
+			fmt.Fprintln(context.Output)
 		}
 	}
-
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintln(context.Error, err.Error())
+	context.Last.Set(request.Model, answer.String())
+	if err != nil && context.Ctx().Err() != nil {
+		// show what arrived before Ctrl-C, there is no context to continue from
+		final.Model, final.Response, final.DoneReason = request.Model, answer.String(), interruptedReason
+		if structured {
+			writeDocument(context, final)
+		} else if format != nil {
+			fmt.Fprint(context.Output, answer.String())
+		}
+		return nil, ErrInterrupted
+	}
+	if err != nil {
+		return nil, err
+	}
+	context.Attachments.Clear()
+	defer context.Stats.record(context, stats) // the footer goes after everything else is shown
+	if format != nil {
+		if err := format.check(context, answer.String()); err != nil {
+			return result, err
+		}
+	}
+	if structured {
+		// the final object with the whole answer in place of the last, empty, piece
+		final.Response = answer.String()
+		return result, writeDocument(context, final)
 	}
 	return result, nil
 }
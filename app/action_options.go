@@ -0,0 +1,61 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Commands to set, unset and list the options sent with generate and chat requests.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+func SetOption(context AppContext, args ...string) (map[string]string, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("not enough arguments provided. Usage: set <option> <value>")
+	}
+	if context.Options == nil {
+		return nil, fmt.Errorf("options are not available")
+	}
+	if err := context.Options.Set(args[0], args[1:]...); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(context.Output, "%s = %s\n", args[0], formatOption(context.Options[args[0]]))
+	return nil, nil
+}
+
+func UnsetOption(context AppContext, args ...string) (map[string]string, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("no option given. Usage: unset <option>|all")
+	}
+	if args[0] == "all" {
+		clear(context.Options)
+		fmt.Fprintln(context.Output, "All options removed.")
+		return nil, nil
+	}
+	if _, found := FindOption(args[0]); !found {
+		return nil, fmt.Errorf("unknown option [%s]", args[0])
+	}
+	delete(context.Options, args[0])
+	fmt.Fprintf(context.Output, "%s removed, the model default will be used.\n", args[0])
+	return nil, nil
+}
+
+func ShowOptions(context AppContext, args ...string) (map[string]string, error) {
+	format := "%-16s %-10s %-20s %s\n"
+	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+	fmt.Fprintf(context.Output, format, "OPTION", "TYPE", "VALUE", "DESCRIPTION")
+	fmt.Fprintf(context.Output, format, "------", "----", "-----", "-----------")
+	for _, spec := range OptionSchema {
+		value := "(default)"
+		if setTo, found := context.Options[spec.Name]; found {
+			value = formatOption(setTo)
+		}
+		fmt.Fprintf(context.Output, format, spec.Name, spec.Kind, value, spec.Help)
+	}
+	fmt.Fprintln(context.Output)
+	return nil, nil
+}
@@ -0,0 +1,55 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Command to list the profiles in the configuration file and switch between them.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+func SwitchProfile(context AppContext, args ...string) (map[string]string, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) < 1 {
+		path, _ := ConfigPath()
+		fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+		fmt.Fprintf(context.Output, "Profiles from %s:\n", path)
+		if len(config.Profiles) == 0 {
+			fmt.Fprintln(context.Output, "No profiles defined.")
+		}
+		for _, name := range config.ProfileNames() {
+			marker := " "
+			if name == context.Profile {
+				marker = "*"
+			}
+			profile := config.Profiles[name]
+			fmt.Fprintf(context.Output, "%s %-15s %-30s %s\n", marker, name, profile.Host, profile.Model)
+		}
+		fmt.Fprintf(context.Output, "Current host: %s\n", context.HostName)
+		return nil, nil
+	}
+
+	profile, err := config.Profile(args[0])
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := ApplyProfile(context, args[0], profile)
+	if err != nil {
+		return nil, err
+	}
+	host := context.HostName
+	if profile.Host != "" {
+		host = profile.Host
+	}
+	fmt.Fprintf(context.Output, "Using profile %s with host %s.\n", args[0], host)
+	return metadata, nil
+}
@@ -10,12 +10,8 @@ package app
 
 import (
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 	"time"
-
-	"github.com/jceaser/ollama-query/lib"
 )
 
 /*
@@ -37,24 +33,16 @@ import (
 		"context_length":8192}]}
 */
 func ExecutePS(context AppContext, args ...string) (map[string]string, error) {
-	resp, err := http.Get(context.HostName + "/api/ps")
+	modelsResponse, err := context.Api().PS(context.Ctx())
 	if err != nil {
-		resp.Body.Close()
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body) // Use io.ReadAll in Go 1.16+
-	if err != nil {
-		return nil, err
+	if context.Structured() {
+		return nil, writeDocument(context, modelsResponse)
 	}
 
 	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
 	fmt.Fprintln(context.Output, "Executing ps command...")
-	modelsResponse, err := lib.StructFromJson[ModelsResponse](body)
-	if err != nil {
-		return nil, err
-	}
 	if len(modelsResponse.Models) == 0 {
 		//return fmt.Errorf("No models found."), nil
 		fmt.Fprintln(context.Output, "No models found.")
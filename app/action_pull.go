@@ -0,0 +1,57 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Code to issue /api/pull requests and show the download progress.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jceaser/ollama-query/client"
+)
+
+/*
+curl http://localhost:11434/api/pull -d '{"model": "llama3.2"}'
+
+streams:
+
+	{"status": "pulling manifest"}
+	{"status": "pulling 6a0746a1ec1a", "digest": "sha256:6a07...", "total": 2019377376, "completed": 241970}
+	{"status": "verifying sha256 digest"}
+	{"status": "writing manifest"}
+	{"status": "success"}
+*/
+func PullModel(context AppContext, args ...string) (map[string]string, error) {
+	flags := context.Flags("pull")
+	insecure := flags.Bool("insecure", false, "allow pulling from a registry without TLS")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() < 1 {
+		return nil, fmt.Errorf("no model name provided. Usage: pull [-insecure] <model>")
+	}
+	model := flags.Arg(0)
+
+	ctx := context.Ctx()
+
+	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+	fmt.Fprintf(context.Output, "Pulling %s, press Ctrl-C to cancel.\n", model)
+
+	start := time.Now()
+	printer := newProgressPrinter(context.Output)
+	status := ""
+	request := &client.PullRequest{Model: model, Insecure: *insecure}
+	err := context.Api().Pull(ctx, request, func(progress client.ProgressResponse) error {
+		printer.Update(progress)
+		status = progress.Status
+		return nil
+	})
+	printer.Done()
+	return nil, reportTransfer(context, ctx, "pull", model, status, start, err)
+}
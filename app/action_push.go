@@ -0,0 +1,81 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Code to issue /api/push requests and show the upload progress.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jceaser/ollama-query/client"
+)
+
+/*
+curl http://localhost:11434/api/push -d '{"model": "mattw/pygmalion:latest"}'
+
+streams:
+
+	{"status": "retrieving manifest"}
+	{"status": "starting upload", "digest": "sha256:bc07c8...", "total": 1928429856}
+	{"status": "pushing sha256:bc07c8...", "digest": "sha256:bc07c8...", "total": 1928429856, "completed": 128000}
+	{"status": "pushing manifest"}
+	{"status": "success"}
+*/
+func PushModel(context AppContext, args ...string) (map[string]string, error) {
+	flags := context.Flags("push")
+	insecure := flags.Bool("insecure", false, "allow pushing to a registry without TLS")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() < 1 {
+		return nil, fmt.Errorf("no model name provided. Usage: push [-insecure] <model>")
+	}
+	model := flags.Arg(0)
+
+	ctx := context.Ctx()
+
+	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+	fmt.Fprintf(context.Output, "Pushing %s, press Ctrl-C to cancel.\n", model)
+
+	start := time.Now()
+	printer := newProgressPrinter(context.Output)
+	status := ""
+	request := &client.PushRequest{Model: model, Insecure: *insecure}
+	err := context.Api().Push(ctx, request, func(progress client.ProgressResponse) error {
+		printer.Update(progress)
+		status = progress.Status
+		return nil
+	})
+	printer.Done()
+	if err := reportTransfer(context, ctx, "push", model, status, start, err); err != nil {
+		return nil, err
+	}
+
+	// the stream does not say what the manifest digest is, so look it up from the local models
+	digest := manifestDigest(context, model)
+	if digest == "" {
+		return nil, nil
+	}
+	fmt.Fprintf(context.Output, "Digest: %s\n", digest)
+	return map[string]string{"digest": digest}, nil
+}
+
+// manifestDigest finds the digest of a model from /api/tags, an empty string if it can't be found
+func manifestDigest(context AppContext, model string) string {
+	models, err := context.Api().Tags(context.Ctx())
+	if err != nil {
+		return ""
+	}
+	for _, found := range models.Models {
+		if found.Name == model || found.Name == model+":latest" {
+			return found.Digest
+		}
+	}
+	return ""
+}
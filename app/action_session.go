@@ -0,0 +1,203 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Commands to save the generate context and chat history to disk and load them back later.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jceaser/ollama-query/lib"
+)
+
+const appDirName = "ollama-query"
+
+var sessionNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// SessionFile is what gets written to disk for a saved session
+type SessionFile struct {
+	Name     string    `json:"name"`
+	SavedAt  time.Time `json:"saved_at"`
+	Model    string    `json:"model,omitempty"`
+	Context  []int     `json:"context,omitempty"` // tokens from the last generate response
+	Messages []Message `json:"messages,omitempty"`
+	Options  Options   `json:"options,omitempty"`
+}
+
+/**************************************/
+// MARK: - Files
+
+// AppDir returns the directory under the users config directory used by this tool, joined with
+// any sub directories given.
+func AppDir(sub ...string) (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{base, appDirName}, sub...)...), nil
+}
+
+func sessionPath(name string) (string, error) {
+	if !sessionNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid session name [%s], use letters, numbers, '.', '_' or '-'", name)
+	}
+	dir, err := AppDir("sessions")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// ListSessions returns the names of all saved sessions, sorted
+func ListSessions() ([]string, error) {
+	dir, err := AppDir("sessions")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func readSession(name string) (SessionFile, error) {
+	path, err := sessionPath(name)
+	if err != nil {
+		return SessionFile{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return SessionFile{}, fmt.Errorf("no session named [%s]", name)
+		}
+		return SessionFile{}, err
+	}
+	return lib.StructFromJson[SessionFile](data)
+}
+
+func writeSession(session SessionFile) error {
+	path, err := sessionPath(session.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := lib.PrettyJsonFromStruct(session, true)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+/**************************************/
+// MARK: - Action
+
+func SessionCommand(context AppContext, args ...string) (map[string]string, error) {
+	usage := "Usage: session save|load|delete <name> or session list"
+	if len(args) < 1 {
+		return nil, fmt.Errorf("no session command given. %s", usage)
+	}
+	if args[0] != "list" && len(args) < 2 {
+		return nil, fmt.Errorf("no session name given. %s", usage)
+	}
+
+	switch args[0] {
+	case "save":
+		session := SessionFile{Name: args[1], SavedAt: time.Now(), Context: context.Context,
+			Options: context.Options}
+		if context.Conversation != nil {
+			session.Model = context.Conversation.Model
+			session.Messages = context.Conversation.Messages
+		}
+		if err := writeSession(session); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(context.Output, "Saved session %s with %d message(s) and %d context token(s).\n",
+			session.Name, len(session.Messages), len(session.Context))
+	case "load":
+		session, err := readSession(args[1])
+		if err != nil {
+			return nil, err
+		}
+		// check everything before changing anything, so a bad session leaves the current one alone
+		options := Options{}
+		if err := options.Restore(session.Options); err != nil {
+			return nil, fmt.Errorf("session %s has a bad option: %v", session.Name, err)
+		}
+		sessionContext, err := json.Marshal(session.Context)
+		if err != nil {
+			return nil, err
+		}
+		if context.Conversation != nil {
+			context.Conversation.Model = session.Model
+			context.Conversation.Messages = session.Messages
+		}
+		if context.Options != nil {
+			clear(context.Options)
+			maps.Copy(context.Options, options)
+		}
+		fmt.Fprintf(context.Output, "Loaded session %s from %s, model %s with %d message(s).\n",
+			session.Name, session.SavedAt.Format("2006-01-02 15:04:05"), modelOrNone(session.Model),
+			len(session.Messages))
+		return map[string]string{"context": string(sessionContext)}, nil
+	case "list":
+		names, err := ListSessions()
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			fmt.Fprintln(context.Output, "No saved sessions.")
+			return nil, nil
+		}
+		fmt.Fprintf(context.Output, "%-25s %-25s %-20s %s\n", "NAME", "MODEL", "SAVED AT", "MESSAGES")
+		fmt.Fprintf(context.Output, "%-25s %-25s %-20s %s\n", "----", "-----", "--------", "--------")
+		for _, name := range names {
+			session, err := readSession(name)
+			if err != nil {
+				lib.Log.Warn.Printf("Could not read session %s: %v\n", name, err)
+				continue
+			}
+			fmt.Fprintf(context.Output, "%-25s %-25s %-20s %d\n", name, session.Model,
+				session.SavedAt.Format("2006-01-02 15:04:05"), len(session.Messages))
+		}
+	case "delete":
+		path, err := sessionPath(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Remove(path); err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("no session named [%s]", args[1])
+			}
+			return nil, err
+		}
+		fmt.Fprintf(context.Output, "Deleted session %s.\n", args[1])
+	default:
+		return nil, fmt.Errorf("unknown session command [%s]. %s", args[0], usage)
+	}
+	return nil, nil
+}
@@ -10,11 +10,7 @@ package app
 
 import (
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
-
-	"github.com/jceaser/ollama-query/lib"
 )
 
 /*
@@ -35,24 +31,16 @@ import (
 */
 
 func ListModels(context AppContext, args ...string) (map[string]string, error) {
-	resp, err := http.Get(context.HostName + "/api/tags")
+	modelsResponse, err := context.Api().Tags(context.Ctx())
 	if err != nil {
-		resp.Body.Close()
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if context.Structured() {
+		return nil, writeDocument(context, modelsResponse)
 	}
 
 	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
 	fmt.Fprintln(context.Output, "Listing models...")
-	modelsResponse, err := lib.StructFromJson[ModelsResponse](body)
-	if err != nil {
-		return nil, err
-	}
 	if len(modelsResponse.Models) == 0 {
 		fmt.Fprintln(context.Output, "No models available.")
 		return nil, nil
@@ -0,0 +1,84 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Command to choose which tools are offered to the model during a chat.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+func ToolsCommand(context AppContext, args ...string) (map[string]string, error) {
+	usage := "Usage: tools [list] | add <builtin>|all | load <file> | remove <name> | clear"
+	if context.Tools == nil {
+		return nil, fmt.Errorf("tools are not available")
+	}
+	toolbox := context.Tools
+
+	subCommand := "list"
+	if len(args) > 0 {
+		subCommand = args[0]
+	}
+	if subCommand != "list" && subCommand != "clear" && len(args) < 2 {
+		return nil, fmt.Errorf("not enough arguments provided. %s", usage)
+	}
+
+	switch subCommand {
+	case "list":
+		fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+		if len(toolbox.Tools()) == 0 {
+			fmt.Fprintln(context.Output, "No tools are sent with chat requests.")
+		}
+		for _, tool := range toolbox.Tools() {
+			kind := "builtin"
+			if len(tool.Command) > 0 {
+				kind = strings.Join(tool.Command, " ")
+			}
+			fmt.Fprintf(context.Output, "%-20s %-25s %s\n", tool.Definition.Function.Name, clip(kind, 25),
+				tool.Definition.Function.Description)
+		}
+		names := []string{}
+		for _, tool := range BuiltinTools() {
+			names = append(names, tool.Definition.Function.Name)
+		}
+		fmt.Fprintf(context.Output, "Built in tools: %s\n", strings.Join(names, ", "))
+	case "add":
+		tools := BuiltinTools()
+		if args[1] != "all" {
+			tool := FindBuiltinTool(args[1])
+			if tool == nil {
+				return nil, fmt.Errorf("no built in tool named [%s]", args[1])
+			}
+			tools = []*RegisteredTool{tool}
+		}
+		for _, tool := range tools {
+			toolbox.Add(tool)
+			fmt.Fprintf(context.Output, "Added %s.\n", tool.Definition.Function.Name)
+		}
+	case "load":
+		tools, err := LoadToolFile(args[1])
+		if err != nil {
+			return nil, err
+		}
+		for _, tool := range tools {
+			toolbox.Add(tool)
+		}
+		fmt.Fprintf(context.Output, "Loaded %d tool(s) from %s.\n", len(tools), args[1])
+	case "remove":
+		if !toolbox.Remove(args[1]) {
+			return nil, fmt.Errorf("no tool named [%s]", args[1])
+		}
+		fmt.Fprintf(context.Output, "Removed %s.\n", args[1])
+	case "clear":
+		toolbox.Clear()
+		fmt.Fprintln(context.Output, "Removed all tools.")
+	default:
+		return nil, fmt.Errorf("unknown tools command [%s]. %s", subCommand, usage)
+	}
+	return nil, nil
+}
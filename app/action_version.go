@@ -9,32 +9,18 @@ Created by Thomas.Cherry.gmail.com
 package app
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 )
 
 // curl http://localhost:11434/api/version -> {"version":"0.1.0"}
 func GetVersion(context AppContext, args ...string) (map[string]string, error) {
-	resp, err := http.Get(context.HostName + "/api/version")
+	versionResponse, err := context.Api().Version(context.Ctx())
 	if err != nil {
-		resp.Body.Close()
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	var versionResponse VersionResponse
-
-	err = json.Unmarshal(body, &versionResponse)
-	if err != nil {
-		return nil, err
+	if context.Structured() {
+		return map[string]string{"version": versionResponse.Version}, writeDocument(context, versionResponse)
 	}
 
 	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
@@ -0,0 +1,132 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Configuration file with named profiles, each setting the host, default model, options, system
+prompt and colors to use.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/jceaser/ollama-query/lib"
+)
+
+/*
+~/.config/ollama-query/config.json looks like:
+
+	{
+	  "default_profile": "home",
+	  "profiles": {
+	    "home": {
+	      "host": "http://localhost:11434",
+	      "model": "llama3.2",
+	      "options": {"temperature": 0.7, "num_ctx": 8192},
+	      "system": "Answer in one paragraph.",
+	      "color": true
+	    },
+	    "lab": {"host": "http://ai.local:11434"}
+	  }
+	}
+*/
+
+// Profile is one named set of defaults from the configuration file
+type Profile struct {
+	Host    string         `json:"host,omitempty"`
+	Model   string         `json:"model,omitempty"`
+	Options map[string]any `json:"options,omitempty"`
+	System  string         `json:"system,omitempty"`
+	Color   *bool          `json:"color,omitempty"`
+}
+
+type Config struct {
+	DefaultProfile string             `json:"default_profile,omitempty"`
+	Profiles       map[string]Profile `json:"profiles,omitempty"`
+}
+
+// ConfigPath returns where the configuration file is read from
+func ConfigPath() (string, error) {
+	return AppDir("config.json")
+}
+
+// LoadConfig reads the configuration file, a missing file is the same as an empty one
+func LoadConfig() (Config, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return Config{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Config{}, nil
+		}
+		return Config{}, err
+	}
+	config, err := lib.StructFromJson[Config](data)
+	if err != nil {
+		return Config{}, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// Profile finds a profile by name
+func (c Config) Profile(name string) (Profile, error) {
+	profile, found := c.Profiles[name]
+	if !found {
+		return Profile{}, fmt.Errorf("no profile named [%s] in the configuration file", name)
+	}
+	return profile, nil
+}
+
+// ProfileNames returns the name of every profile, sorted
+func (c Config) ProfileNames() []string {
+	names := []string{}
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyProfile sets the model, options and colors from a profile. The host, system prompt and default
+// model are values in AppContext, so they are returned as metadata for the caller to store.
+func ApplyProfile(context AppContext, name string, profile Profile) (map[string]string, error) {
+	// a profile without options clears those of the last profile
+	if context.Options != nil {
+		if err := context.Options.Restore(profile.Options); err != nil {
+			return nil, fmt.Errorf("profile %s has a bad option: %v", name, err)
+		}
+	}
+	if context.Conversation != nil && profile.Model != "" {
+		context.Conversation.Model = profile.Model
+	}
+	if profile.Color != nil {
+		lib.ColorEnabled = *profile.Color
+	}
+
+	metadata := map[string]string{"profile": name, "system": profile.System, "model": profile.Model}
+	if profile.Host != "" {
+		metadata["host"] = profile.Host
+	}
+	return metadata, nil
+}
+
+// modelArg picks the model for a command and returns the words left for it. A model named with
+// -model is used first, then the default of the profile, and with neither the first word is the
+// model. The words are never guessed at, so with a default every word belongs to the prompt.
+func (c AppContext) modelArg(flagModel string, args []string) (string, []string) {
+	switch {
+	case flagModel != "":
+		return flagModel, args
+	case c.Model != "":
+		return c.Model, args
+	case len(args) > 0:
+		return args[0], args[1:]
+	}
+	return "", args
+}
@@ -0,0 +1,58 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Chat history which is kept between chat commands so the model sees the whole conversation.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import "slices"
+
+// roles understood by /api/chat
+var ChatRoles = []string{"system", "user", "assistant", "tool"}
+
+const (
+	interruptedMark   = " [interrupted]" // added to the end of answers cut short with Ctrl-C
+	interruptedReason = "interrupted"    // done_reason reported for answers cut short
+)
+
+// Conversation holds the messages sent to and received from the model in the current chat
+type Conversation struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+}
+
+// IsRole returns true if text is one of the chat roles
+func IsRole(text string) bool {
+	return slices.Contains(ChatRoles, text)
+}
+
+// Reset drops all messages and starts over with the given model, an empty model keeps the old one
+func (c *Conversation) Reset(model string) {
+	if model != "" {
+		c.Model = model
+	}
+	c.Messages = nil
+}
+
+// Add appends messages to the history
+func (c *Conversation) Add(messages ...Message) {
+	c.Messages = append(c.Messages, messages...)
+}
+
+// DropLastTurn removes the last user message and everything which came after it, returning the
+// number of messages removed.
+func (c *Conversation) DropLastTurn() int {
+	for i := len(c.Messages) - 1; i >= 0; i-- {
+		if c.Messages[i].Role == "user" {
+			removed := len(c.Messages) - i
+			c.Messages = c.Messages[:i]
+			return removed
+		}
+	}
+	removed := len(c.Messages)
+	c.Messages = nil
+	return removed
+}
@@ -0,0 +1,234 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Model options, like temperature, which are checked against a known list and sent with every
+generate and chat request.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type optionKind string
+
+const (
+	optionInt      optionKind = "integer"
+	optionFloat    optionKind = "number"
+	optionStrings  optionKind = "strings"
+	optionDuration optionKind = "duration"
+)
+
+// OptionSpec describes one option which can be set
+type OptionSpec struct {
+	Name string
+	Kind optionKind
+	Min  float64
+	Max  float64
+	Help string
+}
+
+// keep_alive is not a model option, it is sent at the top level of a request
+const keepAliveOption = "keep_alive"
+
+// OptionSchema lists every option the set command accepts
+var OptionSchema = []OptionSpec{
+	{"temperature", optionFloat, 0, math.Inf(1), "creativity, higher is more random"},
+	{"top_k", optionInt, 0, math.Inf(1), "only sample from the k most likely tokens"},
+	{"top_p", optionFloat, 0, 1, "only sample from tokens making up this much probability"},
+	{"min_p", optionFloat, 0, 1, "drop tokens less likely than this fraction of the best"},
+	{"seed", optionInt, math.Inf(-1), math.Inf(1), "random seed for repeatable answers"},
+	{"num_ctx", optionInt, 1, math.Inf(1), "size of the context window in tokens"},
+	{"num_predict", optionInt, -2, math.Inf(1), "maximum tokens to generate, -1 for no limit"},
+	{"repeat_penalty", optionFloat, 0, math.Inf(1), "how strongly to penalize repeats"},
+	{"stop", optionStrings, 0, 0, "sequences which end the answer"},
+	{keepAliveOption, optionDuration, 0, 0, "how long the model stays loaded, like 5m, or -1"},
+}
+
+// FindOption returns the spec for an option name
+func FindOption(name string) (OptionSpec, bool) {
+	for _, spec := range OptionSchema {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return OptionSpec{}, false
+}
+
+// OptionNames returns the name of every known option
+func OptionNames() []string {
+	names := []string{}
+	for _, spec := range OptionSchema {
+		names = append(names, spec.Name)
+	}
+	return names
+}
+
+/**************************************/
+// MARK: - Options
+
+// Options holds the values which have been set, keyed by option name
+type Options map[string]any
+
+// Set checks values against the schema and stores them. Only stop takes more than one value.
+func (o Options) Set(name string, values ...string) error {
+	spec, found := FindOption(name)
+	if !found {
+		return fmt.Errorf("unknown option [%s], known options are: %s", name,
+			strings.Join(OptionNames(), ", "))
+	}
+	if len(values) < 1 {
+		return fmt.Errorf("no value given for %s", name)
+	}
+	if spec.Kind != optionStrings && len(values) > 1 {
+		return fmt.Errorf("%s takes one value, got %d", name, len(values))
+	}
+
+	var value any
+	switch spec.Kind {
+	case optionInt:
+		number, err := strconv.ParseInt(values[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be an %s, not [%s]", name, spec.Kind, values[0])
+		}
+		if err := spec.inRange(float64(number)); err != nil {
+			return err
+		}
+		value = number
+	case optionFloat:
+		number, err := strconv.ParseFloat(values[0], 64)
+		if err != nil || math.IsNaN(number) {
+			return fmt.Errorf("%s must be a %s, not [%s]", name, spec.Kind, values[0])
+		}
+		if err := spec.inRange(number); err != nil {
+			return err
+		}
+		value = number
+	case optionStrings:
+		value = append([]string{}, values...)
+	case optionDuration:
+		parsed, err := parseKeepAlive(values[0])
+		if err != nil {
+			return err
+		}
+		value = parsed
+	}
+	o[name] = value
+	return nil
+}
+
+func (s OptionSpec) inRange(number float64) error {
+	if number < s.Min || number > s.Max {
+		switch {
+		case math.IsInf(s.Max, 1):
+			return fmt.Errorf("%s must be at least %v", s.Name, s.Min)
+		case math.IsInf(s.Min, -1):
+			return fmt.Errorf("%s must be at most %v", s.Name, s.Max)
+		}
+		return fmt.Errorf("%s must be between %v and %v", s.Name, s.Min, s.Max)
+	}
+	return nil
+}
+
+// parseKeepAlive accepts a go duration like 5m, which is sent as is, or a number of seconds where
+// a negative number keeps the model loaded forever.
+func parseKeepAlive(text string) (any, error) {
+	if seconds, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return seconds, nil
+	}
+	if _, err := time.ParseDuration(text); err != nil {
+		return nil, fmt.Errorf("keep_alive must be a duration like 5m or a number of seconds, not [%s]", text)
+	}
+	return text, nil
+}
+
+// Model returns the options to send as the options field of a request, nil if none are set
+func (o Options) Model() map[string]any {
+	var result map[string]any
+	for name, value := range o {
+		if name == keepAliveOption {
+			continue
+		}
+		if result == nil {
+			result = map[string]any{}
+		}
+		result[name] = value
+	}
+	return result
+}
+
+// KeepAlive returns the keep_alive value for a request, nil if it is not set
+func (o Options) KeepAlive() any {
+	return o[keepAliveOption]
+}
+
+// Names returns the names of the options which have been set, sorted
+func (o Options) Names() []string {
+	names := []string{}
+	for name := range o {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Restore replaces all values with ones read back from JSON, checking them again so numbers get
+// their proper type. Nothing is changed unless every value is good.
+func (o Options) Restore(saved map[string]any) error {
+	restored := Options{}
+	for name, value := range saved {
+		var values []string
+		switch typed := value.(type) {
+		case []any:
+			for _, item := range typed {
+				values = append(values, savedText(item))
+			}
+		default:
+			values = []string{savedText(typed)}
+		}
+		if err := restored.Set(name, values...); err != nil {
+			return err
+		}
+	}
+	clear(o)
+	maps.Copy(o, restored)
+	return nil
+}
+
+// savedText turns a value read from JSON back into text for Set. JSON numbers are all float64, so
+// they are written out in full rather than as 1.2345678e+07, which would not parse as an integer.
+func savedText(value any) string {
+	if number, okay := value.(float64); okay {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// String lists the options which have been set as name=value pairs
+func (o Options) String() string {
+	pairs := []string{}
+	for _, name := range o.Names() {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", name, formatOption(o[name])))
+	}
+	return strings.Join(pairs, " ")
+}
+
+func formatOption(value any) string {
+	if values, okay := value.([]string); okay {
+		quoted := make([]string, len(values))
+		for i, item := range values {
+			quoted[i] = strconv.Quote(item)
+		}
+		return strings.Join(quoted, ", ")
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,71 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Output formats, letting actions write JSON or YAML documents instead of tables for use in scripts.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jceaser/ollama-query/lib"
+)
+
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+)
+
+var OutputFormats = []string{FormatTable, FormatJSON, FormatYAML}
+
+// Structured is true when actions should write a document instead of formatted text
+func (c AppContext) Structured() bool {
+	return c.Format == FormatJSON || c.Format == FormatYAML
+}
+
+// writeDocument writes data to the output as JSON or YAML depending on the format
+func writeDocument(context AppContext, document any) error {
+	var data []byte
+	var err error
+	if context.Format == FormatYAML {
+		data, err = lib.YamlFromStruct(document)
+		data = append([]byte("---\n"), data...) // keep several documents apart in one stream
+	} else {
+		data, err = lib.PrettyJsonFromStruct(document, true)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(context.Output, strings.TrimRight(string(data), "\n"))
+	return nil
+}
+
+// ValidFormat returns an error if format is not one of OutputFormats
+func ValidFormat(format string) error {
+	if !slices.Contains(OutputFormats, format) {
+		return fmt.Errorf("unknown output format [%s], use one of %s", format,
+			strings.Join(OutputFormats, ", "))
+	}
+	return nil
+}
+
+func SetFormat(context AppContext, args ...string) (map[string]string, error) {
+	if len(args) < 1 {
+		format := context.Format
+		if format == "" {
+			format = FormatTable
+		}
+		fmt.Fprintf(context.Output, "Output format is %s.\n", format)
+		return nil, nil
+	}
+	if err := ValidFormat(args[0]); err != nil {
+		return nil, err
+	}
+	return map[string]string{"format": args[0]}, nil
+}
@@ -0,0 +1,95 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Draws the status and per layer progress of model transfers like pull and push.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jceaser/ollama-query/client"
+	"github.com/jceaser/ollama-query/lib"
+)
+
+const progressBarWidth = 30
+
+// progressPrinter writes one line for each status or layer, redrawing the line in place while the
+// same layer keeps reporting progress.
+type progressPrinter struct {
+	output  io.Writer
+	current string // digest, or status when there is no digest, shown on the current line
+	last    client.ProgressResponse
+}
+
+func newProgressPrinter(output io.Writer) *progressPrinter {
+	return &progressPrinter{output: output}
+}
+
+// Update draws a progress object, replacing the current line if it is for the same layer
+func (p *progressPrinter) Update(progress client.ProgressResponse) {
+	key := progress.Digest
+	if key == "" {
+		key = progress.Status
+	}
+	if key == p.current {
+		fmt.Fprint(p.output, "\r"+lib.Escape(lib.ESC_CLEAR_LINE))
+	} else {
+		if p.current != "" {
+			fmt.Fprintln(p.output)
+		}
+		p.current = key
+	}
+	fmt.Fprint(p.output, formatProgress(progress))
+	p.last = progress
+}
+
+// Done ends the current line
+func (p *progressPrinter) Done() {
+	if p.current != "" {
+		fmt.Fprintln(p.output)
+		p.current = ""
+	}
+}
+
+func formatProgress(progress client.ProgressResponse) string {
+	if progress.Total <= 0 {
+		return progress.Status
+	}
+	percent := progress.Completed * 100 / progress.Total
+	return fmt.Sprintf("%-24s %s %3d%% %10s / %s",
+		progress.Status,
+		lib.WrapText(lib.Codes{lib.ESC_GREEN}, lib.ProgressBar(progressBarWidth, progress.Completed, progress.Total)),
+		percent,
+		lib.HumanBytes(progress.Completed),
+		lib.HumanBytes(progress.Total))
+}
+
+// reportTransfer prints the outcome of a pull or push, which only worked if the server finished
+// with a "success" status. A transfer stopped with Ctrl-C returns ErrInterrupted so it is not taken
+// as having worked.
+func reportTransfer(context AppContext, ctx context.Context, verb, model, status string,
+	start time.Time, err error) error {
+	elapsed := time.Since(start).Round(time.Millisecond)
+	switch {
+	case ctx.Err() != nil:
+		fmt.Fprintln(context.Output, lib.WrapText(lib.Codes{lib.ESC_YELLOW},
+			fmt.Sprintf("%s of %s cancelled after %s.", lib.Capitalize(verb), model, elapsed)))
+		return ErrInterrupted
+	case err != nil:
+		fmt.Fprintln(context.Output, lib.WrapText(lib.Codes{lib.ESC_RED},
+			fmt.Sprintf("%s of %s failed after %s.", lib.Capitalize(verb), model, elapsed)))
+		return err
+	case status != "success":
+		return fmt.Errorf("%s of %s ended with status [%s]", verb, model, status)
+	}
+	fmt.Fprintln(context.Output, lib.WrapText(lib.Codes{lib.ESC_GREEN},
+		fmt.Sprintf("%s of %s finished in %s.", lib.Capitalize(verb), model, elapsed)))
+	return nil
+}
@@ -0,0 +1,283 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Prepares the words of a generate or chat command for sending, expanding references to local
+files like @file:main.go, @dir:./pkg and @img:photo.png.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jceaser/ollama-query/client"
+	"github.com/jceaser/ollama-query/lib"
+)
+
+const (
+	imagePrefix = "@img:"
+	filePrefix  = "@file:"
+	dirPrefix   = "@dir:"
+
+	maxImageBytes  = 20 * 1024 * 1024
+	maxFileBytes   = 128 * 1024
+	maxPromptBytes = 512 * 1024
+	maxDirFiles    = 100
+)
+
+// referencePattern finds @img:, @file: and @dir: at the start of a word
+var referencePattern = regexp.MustCompile(`(^|\s)(@img:|@file:|@dir:)(\S+)`)
+
+// Attachments are images waiting to be sent with the next generate or chat prompt
+type Attachments struct {
+	Images []string // paths, read when the prompt is sent
+}
+
+// Pending returns the waiting images
+func (a *Attachments) Pending() []string {
+	if a == nil {
+		return nil
+	}
+	return a.Images
+}
+
+// Clear forgets the waiting images once they have been sent
+func (a *Attachments) Clear() {
+	if a != nil {
+		a.Images = nil
+	}
+}
+
+// preparedPrompt is the text to send along with any images, base64 encoded
+type preparedPrompt struct {
+	Text   string
+	Images []string
+}
+
+// preparePrompt joins the words into the prompt text, expanding @file: and @dir: references into
+// fenced blocks and reading any @img: references and attached images. Images are only allowed when
+// the model has the vision capability. Attached images are left for the caller to clear once the
+// request succeeds, so a prompt which fails can be tried again.
+func preparePrompt(context AppContext, model string, words []string) (preparedPrompt, error) {
+	text := strings.Join(words, " ")
+	var expanded strings.Builder
+	var paths []string
+	budget := maxPromptBytes
+	last := 0
+	// references are found in the whole text so they also work in prompts with several lines
+	for _, match := range referencePattern.FindAllStringSubmatchIndex(text, -1) {
+		kind, target := text[match[4]:match[5]], text[match[6]:match[7]]
+		expanded.WriteString(text[last:match[3]])
+		last = match[1]
+
+		var block string
+		var err error
+		switch kind {
+		case imagePrefix:
+			paths = append(paths, target)
+			continue
+		case filePrefix:
+			block, err = expandFiles(target, &budget)
+		case dirPrefix:
+			block, err = expandDir(target, &budget)
+		}
+		if err != nil {
+			return preparedPrompt{}, err
+		}
+		expanded.WriteString("\n" + block + "\n")
+	}
+	expanded.WriteString(text[last:])
+	paths = append(slices.Clone(context.Attachments.Pending()), paths...)
+
+	prepared := preparedPrompt{Text: strings.TrimSpace(expanded.String())}
+	if len(paths) > 0 {
+		if err := requireVision(context, model); err != nil {
+			return prepared, err
+		}
+		for _, path := range paths {
+			image, err := readImage(path)
+			if err != nil {
+				return prepared, err
+			}
+			prepared.Images = append(prepared.Images, image)
+		}
+	}
+
+	if context.Verbose > 0 {
+		fmt.Fprintln(context.Error, lib.WrapText(lib.Codes{lib.ESC_CYAN},
+			fmt.Sprintf("Prompt for %s, %d bytes and %d image(s):", model, len(prepared.Text), len(prepared.Images))))
+		fmt.Fprintln(context.Error, prepared.Text)
+	}
+	return prepared, nil
+}
+
+// expandFiles returns each file matching the glob pattern as a fenced block
+func expandFiles(pattern string, budget *int) (string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return "", fmt.Errorf("bad file pattern %s: %v", pattern, err)
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no files match %s", pattern)
+	}
+	var blocks []string
+	for _, path := range matches {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return "", fmt.Errorf("%s is a directory, use %s%s", path, dirPrefix, path)
+		}
+		block, err := fenceFile(path, budget)
+		if err != nil {
+			return "", err
+		}
+		blocks = append(blocks, block)
+	}
+	return strings.Join(blocks, "\n\n"), nil
+}
+
+// expandDir returns the text files under a directory as fenced blocks. Hidden files and
+// directories are left out, as are binary and oversized files, which are reported.
+func expandDir(dir string, budget *int) (string, error) {
+	var blocks []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if len(blocks) >= maxDirFiles {
+			return fmt.Errorf("%s has more than %d files", dir, maxDirFiles)
+		}
+		block, err := fenceFile(path, budget)
+		if errors.Is(err, errNotText) {
+			lib.Log.Warn.Printf("Skipping %v\n", err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, block)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(blocks) == 0 {
+		return "", fmt.Errorf("no text files found in %s", dir)
+	}
+	return strings.Join(blocks, "\n\n"), nil
+}
+
+// errNotText marks files which can not be put in a prompt, either binary or too big
+var errNotText = errors.New("not a usable text file")
+
+// fenceFile reads a text file and returns it as a fenced block labeled with its path, taking its
+// size from the budget for the whole prompt
+func fenceFile(path string, budget *int) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > maxFileBytes {
+		return "", fmt.Errorf("%s is %d KB, the limit is %d KB: %w", path, info.Size()>>10, maxFileBytes>>10, errNotText)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return "", fmt.Errorf("%s is binary: %w", path, errNotText)
+	}
+	*budget -= len(data)
+	if *budget < 0 {
+		return "", fmt.Errorf("adding %s makes the prompt bigger than %d KB", path, maxPromptBytes>>10)
+	}
+
+	// use a fence longer than any run of backticks in the file
+	fence := "```"
+	for strings.Contains(string(data), fence) {
+		fence += "`"
+	}
+	language := strings.TrimPrefix(filepath.Ext(path), ".")
+	if name, found := fenceLanguages[language]; found {
+		language = name
+	}
+	content := strings.TrimSuffix(string(data), "\n")
+	return fmt.Sprintf("%s:\n%s%s\n%s\n%s", path, fence, language, content, fence), nil
+}
+
+// fenceLanguages maps file extensions to the language name used on a fenced block where they differ
+var fenceLanguages = map[string]string{
+	"py":  "python",
+	"js":  "javascript",
+	"ts":  "typescript",
+	"rb":  "ruby",
+	"rs":  "rust",
+	"sh":  "bash",
+	"yml": "yaml",
+	"md":  "markdown",
+}
+
+// requireVision asks the server if the model can take images. Older servers do not report
+// capabilities, in which case the images are sent anyway.
+func requireVision(context AppContext, model string) error {
+	details, err := context.Api().Show(context.Ctx(), &client.ShowRequest{Model: model})
+	if err != nil {
+		return fmt.Errorf("could not check if %s supports images: %v", model, err)
+	}
+	if len(details.Capabilities) > 0 && !slices.Contains(details.Capabilities, "vision") {
+		return fmt.Errorf("model %s does not support images, it can do: %s", model,
+			strings.Join(details.Capabilities, ", "))
+	}
+	return nil
+}
+
+// checkImage makes sure a file exists, is not too big and looks like an image
+func checkImage(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("image %s: %v", path, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("image %s is a directory", path)
+	}
+	if info.Size() > maxImageBytes {
+		return nil, fmt.Errorf("image %s is %d MB, the limit is %d MB", path, info.Size()>>20, maxImageBytes>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("image %s: %v", path, err)
+	}
+	if kind := http.DetectContentType(data); !strings.HasPrefix(kind, "image/") {
+		return nil, fmt.Errorf("%s does not look like an image, it is %s", path, kind)
+	}
+	return data, nil
+}
+
+// readImage returns the image file base64 encoded for sending
+func readImage(path string) (string, error) {
+	data, err := checkImage(path)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,92 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Printing answers as they stream in, either as the raw text or rendered from Markdown.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/jceaser/ollama-query/lib"
+)
+
+const (
+	RenderRaw      = "raw"
+	RenderMarkdown = "markdown"
+
+	renderWidth = 80 // width of rules and code block borders
+)
+
+var RenderModes = []string{RenderRaw, RenderMarkdown}
+
+// SetRender shows or changes how answers are printed
+func SetRender(context AppContext, args ...string) (map[string]string, error) {
+	if len(args) < 1 {
+		render := context.Render
+		if render == "" {
+			render = RenderRaw
+		}
+		fmt.Fprintf(context.Output, "Answers are printed as %s.\n", render)
+		return nil, nil
+	}
+	if !slices.Contains(RenderModes, args[0]) {
+		return nil, fmt.Errorf("unknown render mode [%s], use one of %s", args[0],
+			strings.Join(RenderModes, ", "))
+	}
+	return map[string]string{"render": args[0]}, nil
+}
+
+// answerPrinter writes an answer as it streams in, in the colors given when raw
+type answerPrinter struct {
+	out      io.Writer
+	codes    lib.Codes
+	markdown *lib.MarkdownRenderer
+	last     byte // last byte written, to know if the answer ended a line
+}
+
+func newAnswerPrinter(context AppContext, codes lib.Codes) *answerPrinter {
+	printer := &answerPrinter{out: context.Output, codes: codes}
+	if context.Render == RenderMarkdown {
+		printer.markdown = lib.NewMarkdownRenderer(printer, renderWidth)
+	}
+	return printer
+}
+
+// Write sends rendered text on to the output
+func (p *answerPrinter) Write(data []byte) (int, error) {
+	if len(data) > 0 {
+		p.last = data[len(data)-1]
+	}
+	return p.out.Write(data)
+}
+
+// Print adds the next piece of the answer
+func (p *answerPrinter) Print(text string) {
+	if p.markdown != nil {
+		p.markdown.Write([]byte(text))
+		return
+	}
+	if text != "" {
+		if len(p.codes) > 0 {
+			text = lib.WrapText(p.codes, text)
+		}
+		p.Write([]byte(text))
+	}
+}
+
+// Done writes anything held back and ends the line, if anything was written
+func (p *answerPrinter) Done() {
+	if p.markdown != nil {
+		p.markdown.Flush()
+	}
+	if p.last != 0 && p.last != '\n' {
+		p.Write([]byte("\n"))
+	}
+}
@@ -0,0 +1,130 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Statistics for generate and chat: token counts and speeds from the server, along with the time to
+the first token and the total time measured here.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jceaser/ollama-query/client"
+	"github.com/jceaser/ollama-query/lib"
+)
+
+// RequestStats are the statistics for one generate or chat command
+type RequestStats struct {
+	client.Metrics
+	start      time.Time
+	FirstToken time.Duration // from sending the request to the first piece of the answer
+	Wall       time.Duration // from sending the request to the end of the answer
+}
+
+// startStats begins timing a request
+func startStats() *RequestStats {
+	return &RequestStats{start: time.Now()}
+}
+
+// Token notes that part of the answer arrived, the first call sets the time to first token
+func (s *RequestStats) Token() {
+	if s.FirstToken == 0 {
+		s.FirstToken = time.Since(s.start)
+	}
+}
+
+// Finish adds the metrics of a final response and stops the clock
+func (s *RequestStats) Finish(metrics client.Metrics) {
+	s.Metrics.Add(metrics)
+	s.Wall = time.Since(s.start)
+}
+
+// String is the footer shown after an answer
+func (s RequestStats) String() string {
+	return fmt.Sprintf("prompt %d tokens %s | output %d tokens %s | load %s | first token %s | wall %s",
+		s.PromptEvalCount, tokenRate(s.PromptEvalCount, s.PromptEvalDuration),
+		s.EvalCount, tokenRate(s.EvalCount, s.EvalDuration),
+		nanoseconds(s.LoadDuration), s.FirstToken.Round(time.Millisecond), s.Wall.Round(time.Millisecond))
+}
+
+// SessionStats adds up the statistics of every generate and chat since the program started
+type SessionStats struct {
+	Enabled  bool // show the footer after each answer
+	Requests int
+	Totals   client.Metrics
+	Wall     time.Duration
+}
+
+// record adds a request to the totals and shows its footer when enabled
+func (s *SessionStats) record(context AppContext, request *RequestStats) {
+	if s == nil {
+		return
+	}
+	s.Requests++
+	s.Totals.Add(request.Metrics)
+	s.Wall += request.Wall
+	if s.Enabled && !context.Structured() {
+		fmt.Fprintln(context.Output, lib.WrapText(lib.Codes{lib.ESC_FAINT}, request.String()))
+	}
+}
+
+// Stats turns the footer on or off, or shows the totals for the session
+func Stats(context AppContext, args ...string) (map[string]string, error) {
+	stats := context.Stats
+	if stats == nil {
+		return nil, fmt.Errorf("statistics are not available")
+	}
+	if len(args) > 0 {
+		switch args[0] {
+		case "on", "off":
+			stats.Enabled = args[0] == "on"
+			fmt.Fprintf(context.Output, "Statistics after each answer are %s.\n", args[0])
+		case "reset":
+			*stats = SessionStats{Enabled: stats.Enabled}
+			fmt.Fprintln(context.Output, "Statistics reset.")
+		default:
+			return nil, fmt.Errorf("unknown stats command [%s]. Usage: stats [on|off|reset]", args[0])
+		}
+		return nil, nil
+	}
+
+	if context.Structured() {
+		return nil, writeDocument(context, stats)
+	}
+	totals := stats.Totals
+	fmt.Fprintln(context.Output, strings.Repeat("*", 80))
+	fmt.Fprintf(context.Output, "%-16s %d\n", "Requests:", stats.Requests)
+	fmt.Fprintf(context.Output, "%-16s %d, %s\n", "Prompt tokens:", totals.PromptEvalCount,
+		tokenRate(totals.PromptEvalCount, totals.PromptEvalDuration))
+	fmt.Fprintf(context.Output, "%-16s %d, %s\n", "Output tokens:", totals.EvalCount,
+		tokenRate(totals.EvalCount, totals.EvalDuration))
+	fmt.Fprintf(context.Output, "%-16s %s\n", "Load time:", nanoseconds(totals.LoadDuration))
+	fmt.Fprintf(context.Output, "%-16s %s\n", "Server time:", nanoseconds(totals.TotalDuration))
+	fmt.Fprintf(context.Output, "%-16s %s\n", "Wall time:", stats.Wall.Round(time.Millisecond))
+	fmt.Fprintf(context.Output, "%-16s %v\n", "Footer:", onOff(stats.Enabled))
+	return nil, nil
+}
+
+// tokenRate formats tokens per second, or a dash when there is no time to divide by
+func tokenRate(count int, duration int64) string {
+	if duration <= 0 {
+		return "- tok/s"
+	}
+	return fmt.Sprintf("%.1f tok/s", perSecond(count, duration))
+}
+
+func nanoseconds(duration int64) time.Duration {
+	return time.Duration(duration).Round(time.Millisecond)
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
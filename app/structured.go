@@ -0,0 +1,89 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Structured outputs, asking the model to answer in JSON or to follow a JSON schema, then checking
+the answer locally.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jceaser/ollama-query/lib"
+)
+
+// responseFormat is what was asked for with -format, Schema is nil when any JSON will do
+type responseFormat struct {
+	Raw    json.RawMessage
+	Schema map[string]any
+}
+
+// parseFormat reads the -format flag of generate and chat, which is "json" or a schema file
+func parseFormat(value string) (*responseFormat, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if value == "json" {
+		return &responseFormat{Raw: json.RawMessage(`"json"`)}, nil
+	}
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return nil, fmt.Errorf("format must be json or a JSON schema file: %v", err)
+	}
+	schema, err := lib.StructFromJson[map[string]any](data)
+	if err != nil {
+		return nil, fmt.Errorf("schema %s is not a JSON object: %v", value, err)
+	}
+	compact, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	return &responseFormat{Raw: compact, Schema: schema}, nil
+}
+
+// check parses the answer and validates it against the schema. Unless a document is being written,
+// the answer is pretty printed with any problems listed by path.
+func (f *responseFormat) check(context AppContext, answer string) error {
+	var value any
+	if err := json.Unmarshal([]byte(answer), &value); err != nil {
+		return fmt.Errorf("answer is not valid JSON: %v", err)
+	}
+
+	var problems []lib.SchemaError
+	if f.Schema != nil {
+		problems = lib.ValidateSchema(f.Schema, value)
+	}
+
+	if !context.Structured() {
+		// indent the answer itself so the keys stay in the order the model wrote them
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, []byte(strings.TrimSpace(answer)), "", "    "); err != nil {
+			return err
+		}
+		fmt.Fprintln(context.Output, strings.Repeat("-", 80))
+		fmt.Fprintln(context.Output, pretty.String())
+		switch {
+		case f.Schema == nil:
+			fmt.Fprintln(context.Output, lib.WrapText(lib.Codes{lib.ESC_GREEN}, "Answer is valid JSON."))
+		case len(problems) == 0:
+			fmt.Fprintln(context.Output, lib.WrapText(lib.Codes{lib.ESC_GREEN}, "Answer matches the schema."))
+		default:
+			fmt.Fprintln(context.Output, lib.WrapText(lib.Codes{lib.ESC_RED}, "Answer does not match the schema:"))
+			for _, problem := range problems {
+				fmt.Fprintf(context.Output, "  %s\n", problem)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("answer does not match the schema, %d problem(s) found", len(problems))
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Tools the model can call during a chat. Some are built in, others are loaded from a file and run
+an external program which gets the arguments as JSON on stdin and answers on stdout.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jceaser/ollama-query/client"
+	"github.com/jceaser/ollama-query/lib"
+)
+
+const (
+	maxToolRounds    = 10               // tool calls allowed before giving up on a final answer
+	maxToolFileBytes = 64 * 1024        // most of a file read_file will return
+	toolTimeout      = 30 * time.Second // how long an external tool may run
+)
+
+// ToolHandler runs a tool with the arguments the model gave and returns the result for the model
+type ToolHandler func(ctx context.Context, arguments map[string]any) (string, error)
+
+// RegisteredTool is a tool definition sent to the model and the code which runs it
+type RegisteredTool struct {
+	Definition client.Tool
+	Command    []string // external program, empty for built in tools
+	Handler    ToolHandler
+}
+
+// Toolbox holds the tools sent with every chat request, in the order they were added
+type Toolbox struct {
+	tools []*RegisteredTool
+}
+
+// Add registers a tool, replacing any tool with the same name
+func (t *Toolbox) Add(tool *RegisteredTool) {
+	t.Remove(tool.Definition.Function.Name)
+	t.tools = append(t.tools, tool)
+}
+
+// Remove drops a tool by name, returning false if there was no such tool
+func (t *Toolbox) Remove(name string) bool {
+	for i, tool := range t.tools {
+		if tool.Definition.Function.Name == name {
+			t.tools = append(t.tools[:i], t.tools[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Clear removes all tools
+func (t *Toolbox) Clear() {
+	t.tools = nil
+}
+
+// Find returns the tool with the name, nil if there is none
+func (t *Toolbox) Find(name string) *RegisteredTool {
+	if t == nil {
+		return nil
+	}
+	for _, tool := range t.tools {
+		if tool.Definition.Function.Name == name {
+			return tool
+		}
+	}
+	return nil
+}
+
+// Definitions returns what to send as the tools of a chat request, nil when there are none
+func (t *Toolbox) Definitions() []client.Tool {
+	if t == nil || len(t.tools) == 0 {
+		return nil
+	}
+	definitions := []client.Tool{}
+	for _, tool := range t.tools {
+		definitions = append(definitions, tool.Definition)
+	}
+	return definitions
+}
+
+// Tools returns the registered tools
+func (t *Toolbox) Tools() []*RegisteredTool {
+	return t.tools
+}
+
+// Call runs the tool a model asked for. Failures are given back to the model as the result so it
+// can try again, the error is also returned for reporting.
+func (t *Toolbox) Call(ctx context.Context, call client.ToolCall) (Message, error) {
+	name := call.Function.Name
+	result := Message{Role: "tool", ToolName: name}
+	tool := t.Find(name)
+	if tool == nil {
+		result.Content = fmt.Sprintf("error: there is no tool named %s", name)
+		return result, fmt.Errorf("model asked for unknown tool [%s]", name)
+	}
+	content, err := tool.Handler(ctx, call.Function.Arguments)
+	if err != nil {
+		result.Content = "error: " + err.Error()
+		return result, err
+	}
+	result.Content = content
+	return result, nil
+}
+
+/**************************************/
+// MARK: - Built in tools
+
+func objectSchema(required []string, properties map[string]any) map[string]any {
+	return map[string]any{"type": "object", "required": required, "properties": properties}
+}
+
+func stringProperty(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description}
+}
+
+func newTool(name, description string, parameters map[string]any, handler ToolHandler) *RegisteredTool {
+	return &RegisteredTool{
+		Definition: client.Tool{Type: "function", Function: client.ToolFunction{
+			Name: name, Description: description, Parameters: parameters}},
+		Handler: handler,
+	}
+}
+
+// BuiltinTools returns a new copy of every tool which does not need an external program
+func BuiltinTools() []*RegisteredTool {
+	return []*RegisteredTool{
+		newTool("current_time", "Get the current date and time, optionally in an IANA time zone",
+			objectSchema([]string{}, map[string]any{
+				"timezone": stringProperty("time zone like America/New_York, local time if empty")}),
+			currentTimeTool),
+		newTool("read_file", "Read a text file from the current directory or below it",
+			objectSchema([]string{"path"}, map[string]any{
+				"path": stringProperty("relative path of the file")}),
+			readFileTool),
+		newTool("calculator", "Evaluate an arithmetic expression with + - * / % ^, parentheses, pi, e and functions like sqrt()",
+			objectSchema([]string{"expression"}, map[string]any{
+				"expression": stringProperty("expression to evaluate, like 2 * (3 + 4)")}),
+			calculatorTool),
+	}
+}
+
+// FindBuiltinTool returns the built in tool with the name, nil if there is none
+func FindBuiltinTool(name string) *RegisteredTool {
+	for _, tool := range BuiltinTools() {
+		if tool.Definition.Function.Name == name {
+			return tool
+		}
+	}
+	return nil
+}
+
+func stringArgument(arguments map[string]any, name string) string {
+	value, found := arguments[name]
+	if !found || value == nil {
+		return ""
+	}
+	if text, okay := value.(string); okay {
+		return text
+	}
+	return fmt.Sprint(value)
+}
+
+func currentTimeTool(ctx context.Context, arguments map[string]any) (string, error) {
+	now := time.Now()
+	if zone := stringArgument(arguments, "timezone"); zone != "" {
+		location, err := time.LoadLocation(zone)
+		if err != nil {
+			return "", fmt.Errorf("unknown time zone %s", zone)
+		}
+		now = now.In(location)
+	}
+	return now.Format("Monday, 2006-01-02 15:04:05 MST"), nil
+}
+
+func readFileTool(ctx context.Context, arguments map[string]any) (string, error) {
+	path := stringArgument(arguments, "path")
+	if path == "" {
+		return "", fmt.Errorf("no path given")
+	}
+	// keep the model inside the directory the tool was started from, the root also stops symbolic
+	// links from leading out of it
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("only files under the current directory can be read")
+	}
+	root, err := os.OpenRoot(".")
+	if err != nil {
+		return "", err
+	}
+	defer root.Close()
+	file, err := root.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxToolFileBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxToolFileBytes {
+		return string(data[:maxToolFileBytes]) + "\n[file cut off]", nil
+	}
+	return string(data), nil
+}
+
+func calculatorTool(ctx context.Context, arguments map[string]any) (string, error) {
+	value, err := lib.Calculate(stringArgument(arguments, "expression"))
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64), nil
+}
+
+/**************************************/
+// MARK: - External tools
+
+/*
+A tool file holds a list of tools, each running a program:
+
+	[
+	  {
+	    "name": "get_weather",
+	    "description": "Get the weather for a city",
+	    "parameters": {"type": "object", "required": ["city"],
+	      "properties": {"city": {"type": "string"}}},
+	    "command": ["./weather.sh"]
+	  }
+	]
+
+Commands are relative to the file. A built in tool can be listed as {"builtin": "calculator"}.
+*/
+type toolFileEntry struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+	Command     []string       `json:"command"`
+	Builtin     string         `json:"builtin"`
+}
+
+// LoadToolFile reads tool definitions from a JSON file
+func LoadToolFile(path string) ([]*RegisteredTool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := lib.StructFromJson[[]toolFileEntry](data)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+
+	tools := []*RegisteredTool{}
+	for i, entry := range entries {
+		if entry.Builtin != "" {
+			tool := FindBuiltinTool(entry.Builtin)
+			if tool == nil {
+				return nil, fmt.Errorf("tool %d: no built in tool named [%s]", i+1, entry.Builtin)
+			}
+			tools = append(tools, tool)
+			continue
+		}
+		if entry.Name == "" || len(entry.Command) == 0 {
+			return nil, fmt.Errorf("tool %d: a name and a command are required", i+1)
+		}
+		if entry.Parameters == nil {
+			entry.Parameters = objectSchema([]string{}, map[string]any{})
+		}
+		command := append([]string{}, entry.Command...)
+		if strings.HasPrefix(command[0], ".") {
+			command[0] = filepath.Join(filepath.Dir(path), command[0])
+		}
+		tool := newTool(entry.Name, entry.Description, entry.Parameters, externalTool(command))
+		tool.Command = command
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+// externalTool runs a program with the arguments as JSON on stdin, the output is the result
+func externalTool(command []string) ToolHandler {
+	return func(ctx context.Context, arguments map[string]any) (string, error) {
+		input, err := json.Marshal(arguments)
+		if err != nil {
+			return "", err
+		}
+		ctx, cancel := context.WithTimeout(ctx, toolTimeout)
+		defer cancel()
+
+		var stdout, stderr bytes.Buffer
+		program := exec.CommandContext(ctx, command[0], command[1:]...)
+		program.Stdin = bytes.NewReader(input)
+		program.Stdout = &stdout
+		program.Stderr = &stderr
+		if err := program.Run(); err != nil {
+			if message := strings.TrimSpace(stderr.String()); message != "" {
+				return "", fmt.Errorf("%v: %s", err, message)
+			}
+			return "", err
+		}
+		return strings.TrimSpace(stdout.String()), nil
+	}
+}
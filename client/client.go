@@ -0,0 +1,324 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+A reusable client for the Ollama server API. Methods return structs, or call back with each object
+of a streamed response, and never print anything so the package can be embedded in other tools.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/**************************************/
+// MARK: - Client
+
+const (
+	DefaultHost    = "http://localhost:11434"
+	DefaultTimeout = 30 * time.Second
+)
+
+// Client talks to one Ollama server. The zero value is not usable, call New().
+type Client struct {
+	BaseURL string        // server URL, like http://localhost:11434
+	HTTP    *http.Client  // transport used for every request
+	Headers http.Header   // extra headers sent with every request
+	Timeout time.Duration // limit for quick requests, streams and those which may load a model are only limited by ctx
+}
+
+// StatusError is returned when the server answers with a non 2xx status or streams an error.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Message    string `json:"error"`
+}
+
+func (e StatusError) Error() string {
+	switch {
+	case e.Status != "" && e.Message != "":
+		return fmt.Sprintf("%s: %s", e.Status, e.Message)
+	case e.Message != "":
+		return e.Message
+	default:
+		return e.Status
+	}
+}
+
+// New creates a client for the server at baseURL, an empty URL uses DefaultHost
+func New(baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultHost
+	}
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		HTTP:    &http.Client{},
+		Headers: http.Header{},
+		Timeout: DefaultTimeout,
+	}
+}
+
+/**************************************/
+// MARK: - Request helpers
+
+// do sends a request with an optional JSON body and checks the status code. Caller must close the
+// body of the returned response.
+func (c *Client) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	if body == nil {
+		return c.send(ctx, method, path, nil, "")
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(ctx, method, path, bytes.NewReader(data), "application/json")
+}
+
+// send issues a request with the client headers and turns any non 2xx status into a StatusError
+func (c *Client) send(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.Headers {
+		for _, value := range values {
+			request.Header.Add(key, value)
+		}
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	request.Header.Set("Accept", "application/x-ndjson, application/json")
+
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		statusError := StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+		if data, err := io.ReadAll(resp.Body); err == nil && len(data) > 0 {
+			if json.Unmarshal(data, &statusError) != nil {
+				statusError.Message = strings.TrimSpace(string(data))
+			}
+		}
+		return nil, statusError
+	}
+	return resp, nil
+}
+
+// call sends a request, limited by Timeout, and decodes a single JSON document into result, which
+// may be nil
+func (c *Client) call(ctx context.Context, method, path string, body, result any) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	return c.fetch(ctx, method, path, body, result)
+}
+
+// fetch is call without the Timeout, for requests which may have to load a model first and so can
+// take as long as the model takes to load
+func (c *Client) fetch(ctx context.Context, method, path string, body, result any) error {
+	resp, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if result == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// stream sends a request and calls fn with each JSON object in the response until the stream ends,
+// fn returns an error, or the server sends an error object.
+func stream[T any](ctx context.Context, c *Client, method, path string, body any, fn func(T) error) error {
+	resp, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var streamError StatusError
+		if json.Unmarshal(raw, &streamError) == nil && streamError.Message != "" {
+			return streamError
+		}
+
+		var value T
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+		if err := fn(value); err != nil {
+			return err
+		}
+	}
+}
+
+/**************************************/
+// MARK: - API
+
+// Generate calls /api/generate and hands each streamed response to fn
+func (c *Client) Generate(ctx context.Context, request *GenerateRequest, fn GenerateResponseFunc) error {
+	return stream(ctx, c, http.MethodPost, "/api/generate", request, fn)
+}
+
+// Chat calls /api/chat and hands each streamed response to fn
+func (c *Client) Chat(ctx context.Context, request *ChatRequest, fn ChatResponseFunc) error {
+	return stream(ctx, c, http.MethodPost, "/api/chat", request, fn)
+}
+
+// Show calls /api/show to get the details of one model, it is not limited by Timeout
+func (c *Client) Show(ctx context.Context, request *ShowRequest) (*ShowResponse, error) {
+	var response ShowResponse
+	if err := c.fetch(ctx, http.MethodPost, "/api/show", request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Tags calls /api/tags to list the models available on the server
+func (c *Client) Tags(ctx context.Context) (*ModelsResponse, error) {
+	var response ModelsResponse
+	if err := c.call(ctx, http.MethodGet, "/api/tags", nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// PS calls /api/ps to list the models currently loaded into memory
+func (c *Client) PS(ctx context.Context) (*ModelsResponse, error) {
+	var response ModelsResponse
+	if err := c.call(ctx, http.MethodGet, "/api/ps", nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Version calls /api/version
+func (c *Client) Version(ctx context.Context) (*VersionResponse, error) {
+	var response VersionResponse
+	if err := c.call(ctx, http.MethodGet, "/api/version", nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Pull calls /api/pull to download a model, reporting progress to fn
+func (c *Client) Pull(ctx context.Context, request *PullRequest, fn ProgressResponseFunc) error {
+	return stream(ctx, c, http.MethodPost, "/api/pull", request, fn)
+}
+
+// Push calls /api/push to upload a model to a registry, reporting progress to fn
+func (c *Client) Push(ctx context.Context, request *PushRequest, fn ProgressResponseFunc) error {
+	return stream(ctx, c, http.MethodPost, "/api/push", request, fn)
+}
+
+// Embed calls /api/embed to get an embedding vector for each input. It is not limited by Timeout
+// as the model may have to be loaded first.
+func (c *Client) Embed(ctx context.Context, request *EmbedRequest) (*EmbedResponse, error) {
+	var response EmbedResponse
+	if err := c.fetch(ctx, http.MethodPost, "/api/embed", request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Copy calls /api/copy to make a new name for an existing model
+func (c *Client) Copy(ctx context.Context, request *CopyRequest) error {
+	return c.call(ctx, http.MethodPost, "/api/copy", request, nil)
+}
+
+// Delete calls /api/delete to remove a model and any data not used by other models
+func (c *Client) Delete(ctx context.Context, request *DeleteRequest) error {
+	return c.call(ctx, http.MethodDelete, "/api/delete", request, nil)
+}
+
+// Create calls /api/create, reporting the status of each step to fn
+func (c *Client) Create(ctx context.Context, request *CreateRequest, fn ProgressResponseFunc) error {
+	return stream(ctx, c, http.MethodPost, "/api/create", request, fn)
+}
+
+// HasBlob checks if the server already has a file with the digest, like "sha256:abc..."
+func (c *Client) HasBlob(ctx context.Context, digest string) (bool, error) {
+	err := c.call(ctx, http.MethodHead, "/api/blobs/"+digest, nil, nil)
+	var statusError StatusError
+	if errors.As(err, &statusError) && statusError.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CreateBlob uploads a file to the server so it can be used by Create. The digest is the sha256 of
+// the content and is checked by the server. No timeout is applied as files can be very large.
+func (c *Client) CreateBlob(ctx context.Context, digest string, content io.Reader) error {
+	resp, err := c.send(ctx, http.MethodPost, "/api/blobs/"+digest, content, "application/octet-stream")
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+/**************************************/
+// MARK: - Hosts
+
+// ParseHost turns a host in any of the forms accepted by OLLAMA_HOST, like "0.0.0.0", ":8080" or
+// "https://example.com", into a base URL. The port defaults to 11434 unless a scheme is given.
+func ParseHost(host string) string {
+	defaultPort := "11434"
+	scheme, hostPort, found := strings.Cut(strings.TrimSpace(host), "://")
+	switch {
+	case !found:
+		scheme, hostPort = "http", strings.TrimSpace(host)
+	case scheme == "http":
+		defaultPort = "80"
+	case scheme == "https":
+		defaultPort = "443"
+	}
+
+	hostPort, path, _ := strings.Cut(hostPort, "/")
+	name, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		name, port = strings.Trim(hostPort, "[]"), defaultPort
+	}
+	if name == "" {
+		name = "127.0.0.1"
+	}
+	if port == "" {
+		port = defaultPort
+	}
+
+	result := scheme + "://" + net.JoinHostPort(name, port)
+	if path = strings.Trim(path, "/"); path != "" {
+		result += "/" + path
+	}
+	return result
+}
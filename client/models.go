@@ -0,0 +1,251 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Request and response structures for the Ollama server API.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package client
+
+import "encoding/json"
+
+/**************************************/
+// MARK: - Models
+
+// Model represents an individual model.
+type Model struct {
+	Name          string  `json:"name"`
+	Model         string  `json:"model"`
+	ModifiedAt    string  `json:"modified_at,omitempty"`
+	Size          int64   `json:"size"`
+	Digest        string  `json:"digest"`
+	Details       Details `json:"details"`
+	ExpiresAt     string  `json:"expires_at,omitempty"`
+	SizeVRAM      int64   `json:"size_vram,omitempty"`
+	ContextLength int64   `json:"context_length,omitempty"`
+}
+
+// Details contains additional information about the model.
+type Details struct {
+	ParentModel       string   `json:"parent_model"`
+	Format            string   `json:"format"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families"`
+	ParameterSize     string   `json:"parameter_size"`
+	QuantizationLevel string   `json:"quantization_level"`
+}
+
+// ModelsResponse represents the overall structure of the JSON response from /api/tags and /api/ps
+type ModelsResponse struct {
+	Models []Model `json:"models"`
+}
+
+type VersionResponse struct {
+	Version string `json:"version"`
+}
+
+/**************************************/
+// MARK: - Show
+
+type ShowRequest struct {
+	Model   string `json:"model"`
+	Verbose bool   `json:"verbose,omitempty"`
+}
+
+// ShowResponse is the result of /api/show
+type ShowResponse struct {
+	Modelfile    string         `json:"modelfile,omitempty"`
+	Parameters   string         `json:"parameters,omitempty"`
+	Template     string         `json:"template,omitempty"`
+	System       string         `json:"system,omitempty"`
+	License      string         `json:"license,omitempty"`
+	Details      Details        `json:"details"`
+	ModelInfo    map[string]any `json:"model_info,omitempty"` // flat keys like "general.architecture"
+	Capabilities []string       `json:"capabilities,omitempty"`
+	ModifiedAt   string         `json:"modified_at,omitempty"`
+}
+
+/**************************************/
+// MARK: - Generate
+
+type GenerateRequest struct {
+	Model   string   `json:"model"`
+	Prompt  string   `json:"prompt"`
+	System  string   `json:"system,omitempty"`
+	Context []int    `json:"context,omitempty"` // returned by the last response, continues a conversation
+	Images  []string `json:"images,omitempty"`  // base64 encoded images for vision models
+	Stream  *bool    `json:"stream,omitempty"`
+
+	Options   map[string]any  `json:"options,omitempty"`    // model parameters like temperature
+	KeepAlive any             `json:"keep_alive,omitempty"` // duration like "5m", or seconds as a number
+	Format    json.RawMessage `json:"format,omitempty"`     // "json" or a JSON schema for the answer
+}
+
+// Metrics are the counts and timings, in nanoseconds, sent with the final response of a generate or
+// chat
+type Metrics struct {
+	TotalDuration      int64 `json:"total_duration,omitempty"`
+	LoadDuration       int64 `json:"load_duration,omitempty"`
+	PromptEvalCount    int   `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64 `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int   `json:"eval_count,omitempty"`
+	EvalDuration       int64 `json:"eval_duration,omitempty"`
+}
+
+// Add sums the counts and timings of another response into these
+func (m *Metrics) Add(other Metrics) {
+	m.TotalDuration += other.TotalDuration
+	m.LoadDuration += other.LoadDuration
+	m.PromptEvalCount += other.PromptEvalCount
+	m.PromptEvalDuration += other.PromptEvalDuration
+	m.EvalCount += other.EvalCount
+	m.EvalDuration += other.EvalDuration
+}
+
+// GenerateResponse is one object of the /api/generate stream, the last one has Done set along with
+// the context and timing values.
+type GenerateResponse struct {
+	Model     string `json:"model"`
+	CreatedAt string `json:"created_at"`
+	Response  string `json:"response"`
+	Done      bool   `json:"done"`
+
+	// only sent with the final response
+	Context    []int  `json:"context,omitempty"`
+	DoneReason string `json:"done_reason,omitempty"`
+	Metrics
+}
+
+type GenerateResponseFunc func(GenerateResponse) error
+
+/**************************************/
+// MARK: - Chat
+
+type Message struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	Images    []string   `json:"images,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"` // asked for by the assistant
+	ToolName  string     `json:"tool_name,omitempty"`  // which tool a tool message is the result of
+}
+
+// Tool describes a function the model may ask to have called, Parameters is a JSON schema
+type Tool struct {
+	Type     string       `json:"type"` // always "function"
+	Function ToolFunction `json:"function"`
+}
+
+type ToolFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+// ToolCall is a request from the model to run one of the tools
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"`
+}
+
+type ToolCallFunction struct {
+	Index     int            `json:"index,omitempty"`
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+type ChatRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Tools    []Tool    `json:"tools,omitempty"`
+	Stream   *bool     `json:"stream,omitempty"`
+
+	Options   map[string]any  `json:"options,omitempty"`    // model parameters like temperature
+	KeepAlive any             `json:"keep_alive,omitempty"` // duration like "5m", or seconds as a number
+	Format    json.RawMessage `json:"format,omitempty"`     // "json" or a JSON schema for the answer
+}
+
+// ChatResponse is one object of the /api/chat stream, the last one has Done set along with the
+// timing values.
+type ChatResponse struct {
+	Model     string  `json:"model"`
+	CreatedAt string  `json:"created_at"`
+	Message   Message `json:"message"`
+	Done      bool    `json:"done"`
+
+	// only sent with the final response
+	DoneReason string `json:"done_reason,omitempty"`
+	Metrics
+}
+
+type ChatResponseFunc func(ChatResponse) error
+
+/**************************************/
+// MARK: - Pull and Push
+
+type PullRequest struct {
+	Model    string `json:"model"`
+	Insecure bool   `json:"insecure,omitempty"` // allow registries without TLS
+	Stream   *bool  `json:"stream,omitempty"`
+}
+
+type PushRequest struct {
+	Model    string `json:"model"` // must include the registry namespace, like registry/name:tag
+	Insecure bool   `json:"insecure,omitempty"`
+	Stream   *bool  `json:"stream,omitempty"`
+}
+
+// ProgressResponse is one status object streamed while a model is transferred, layers report the
+// digest along with how many bytes have been completed.
+type ProgressResponse struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+}
+
+type ProgressResponseFunc func(ProgressResponse) error
+
+/**************************************/
+// MARK: - Copy, Delete and Create
+
+type CopyRequest struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+type DeleteRequest struct {
+	Model string `json:"model"`
+}
+
+// CreateRequest builds a new model from an existing one, or from files already uploaded as blobs
+type CreateRequest struct {
+	Model      string            `json:"model"`
+	From       string            `json:"from,omitempty"`
+	Files      map[string]string `json:"files,omitempty"`    // file name to blob digest
+	Adapters   map[string]string `json:"adapters,omitempty"` // file name to blob digest
+	Template   string            `json:"template,omitempty"`
+	License    []string          `json:"license,omitempty"`
+	System     string            `json:"system,omitempty"`
+	Parameters map[string]any    `json:"parameters,omitempty"`
+	Messages   []Message         `json:"messages,omitempty"`
+	Quantize   string            `json:"quantize,omitempty"`
+	Stream     *bool             `json:"stream,omitempty"`
+}
+
+/**************************************/
+// MARK: - Embed
+
+type EmbedRequest struct {
+	Model    string   `json:"model"`
+	Input    []string `json:"input"`
+	Truncate *bool    `json:"truncate,omitempty"` // cut inputs to fit the context, defaults to true
+}
+
+// EmbedResponse has one embedding for each input, in the same order
+type EmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float64 `json:"embeddings"`
+	TotalDuration   int64       `json:"total_duration,omitempty"`
+	LoadDuration    int64       `json:"load_duration,omitempty"`
+	PromptEvalCount int         `json:"prompt_eval_count,omitempty"`
+}
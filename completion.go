@@ -0,0 +1,204 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Tab completion of commands and their arguments: model names, roles, options, sessions and files.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jceaser/ollama-query/app"
+)
+
+const (
+	modelCacheTTL   = 30 * time.Second
+	modelTagTimeout = 2 * time.Second
+)
+
+// completer completes the word under the cursor based on the command it is part of
+type completer struct {
+	state   *app.AppContext
+	models  []string
+	fetched time.Time
+}
+
+// Complete is a liner word completer, it returns the line before the word, the possible words,
+// and the line after the cursor
+func (c *completer) Complete(line string, pos int) (head string, completions []string, tail string) {
+	runes := []rune(line)
+	before, tail := string(runes[:pos]), string(runes[pos:])
+
+	// only the last command on the line matters
+	segment := before
+	if i := strings.LastIndexAny(segment, ";&"); i >= 0 {
+		segment = segment[i+1:]
+	}
+	words := strings.Fields(segment)
+	word := ""
+	if len(words) > 0 && !strings.HasSuffix(segment, " ") {
+		word = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	head = before[:len(before)-len(word)]
+
+	var candidates []string
+	for _, prefix := range []string{"@file:", "@dir:", "@img:"} {
+		if path, found := strings.CutPrefix(word, prefix); found {
+			for _, match := range completePath(path) {
+				completions = append(completions, prefix+match)
+			}
+			return head, completions, tail
+		}
+	}
+	if len(words) == 0 {
+		candidates = actions.Triggers()
+	} else {
+		candidates = c.arguments(words[0], words[1:])
+	}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) && !slices.Contains(completions, candidate) {
+			completions = append(completions, candidate)
+		}
+	}
+	slices.Sort(completions)
+	return head, completions, tail
+}
+
+// arguments returns the words which can come next after a command and the arguments already typed
+func (c *completer) arguments(command string, args []string) []string {
+	item, err := actions.Find(command)
+	if err != nil {
+		return nil
+	}
+	args = skipFlags(args, actionDetails[item.Name].Flags)
+	switch item.Name {
+	case "Chat":
+		// the model can be left off, so a role may come first
+		if len(args) == 0 {
+			return slices.Concat(c.modelNames(), app.ChatRoles)
+		}
+		if len(args) == 1 && !app.IsRole(args[0]) {
+			return app.ChatRoles
+		}
+	case "Generate", "Show", "Delete", "Copy", "Push", "Embed", "Edit":
+		if len(args) == 0 {
+			return c.modelNames()
+		}
+	case "Bench":
+		// any number of models follow the prompt file
+		if len(args) > 0 {
+			return c.modelNames()
+		}
+	case "Conversation":
+		if len(args) == 0 {
+			return []string{"new", "show", "undo", "model"}
+		}
+		if len(args) == 1 && (args[0] == "new" || args[0] == "model") {
+			return c.modelNames()
+		}
+	case "Set":
+		if len(args) == 0 {
+			return app.OptionNames()
+		}
+	case "Unset":
+		if len(args) == 0 {
+			return append(c.state.Options.Names(), "all")
+		}
+	case "Session":
+		if len(args) == 0 {
+			return []string{"save", "load", "list", "delete"}
+		}
+		if len(args) == 1 && (args[0] == "load" || args[0] == "delete") {
+			names, _ := app.ListSessions()
+			return names
+		}
+	case "Tools":
+		if len(args) == 0 {
+			return []string{"list", "add", "load", "remove", "clear"}
+		}
+		if len(args) == 1 && args[0] == "add" {
+			names := []string{"all"}
+			for _, tool := range app.BuiltinTools() {
+				names = append(names, tool.Definition.Function.Name)
+			}
+			return names
+		}
+	case "Blocks":
+		if len(args) == 0 {
+			return []string{"list", "save", "pipe", "copy"}
+		}
+	case "Stats":
+		if len(args) == 0 {
+			return []string{"on", "off", "reset"}
+		}
+	case "Format":
+		if len(args) == 0 {
+			return app.OutputFormats
+		}
+	case "Render":
+		if len(args) == 0 {
+			return app.RenderModes
+		}
+	case "Profile":
+		if len(args) == 0 {
+			config, _ := app.LoadConfig()
+			return config.ProfileNames()
+		}
+	case "Help":
+		if len(args) == 0 {
+			return actions.Triggers()
+		}
+	}
+	return nil
+}
+
+// modelNames returns the models on the server, asking again once the cached list is too old. A
+// server which can not be reached gives no names rather than holding up the prompt.
+func (c *completer) modelNames() []string {
+	if time.Since(c.fetched) < modelCacheTTL {
+		return c.models
+	}
+	c.fetched = time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), modelTagTimeout)
+	defer cancel()
+	response, err := c.state.Api().Tags(ctx)
+	if err != nil {
+		return c.models
+	}
+	c.models = nil
+	for _, model := range response.Models {
+		c.models = append(c.models, model.Name)
+	}
+	return c.models
+}
+
+// completePath returns the files and directories starting with path, directories end with a "/".
+// Hidden files are only included when the name being typed starts with a ".".
+func completePath(path string) []string {
+	dir, base := filepath.Split(path)
+	entries, err := os.ReadDir(filepath.Clean(dir + "."))
+	if err != nil {
+		return nil
+	}
+	var matches []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		if entry.IsDir() {
+			name += "/"
+		}
+		matches = append(matches, dir+name)
+	}
+	return matches
+}
@@ -0,0 +1,313 @@
+// **********************************************************************************************100
+/*
+Ollama Query - A simple command-line tool to interact with the Ollama server API.
+Detailed help for each action, and checking the arguments given to an action against its usage.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// actionFlag describes a flag an action takes before its other arguments
+type actionFlag struct {
+	Name  string
+	Value string // name of the value the flag takes, empty for flags which are on or off
+	Help  string
+}
+
+// actionDetail is the help shown by "help <command>", beyond the one line in the menu
+type actionDetail struct {
+	Details  string
+	Flags    []actionFlag
+	Examples []string
+}
+
+var formatFlag = actionFlag{"format", "f", "json, or a JSON schema file the answer must follow"}
+
+// modelFlag names the model for the commands which otherwise take it from the first word or the profile
+var modelFlag = actionFlag{"model", "name", "model to use instead of the first word or the profile's"}
+
+// actionDetails is keyed by the name of the action
+var actionDetails = map[string]actionDetail{
+	"Attach": {
+		Details: "Queues images to send with the next generate or chat prompt, which must be to a " +
+			"model with vision. With no arguments the queued images are listed.",
+		Examples: []string{"attach photo.png chart.png", "attach", "attach clear"},
+	},
+	"Bench": {
+		Details: "Sends each prompt in a file, one to a line, to each model a number of times and " +
+			"compares the mean, p50 and p95 output tokens per second, the load time and the time " +
+			"to the first token. Prompts are sent without the system prompt or context, with the " +
+			"options set. A CSV export puts the runs in the file given and the comparison in one " +
+			"ending in -summary.csv.",
+		Flags: []actionFlag{
+			{"n", "runs", "number of times to send each prompt to each model"},
+			{"unload", "", "unload the model after each run so every run includes loading it"},
+			{"export", "file", "also write the results to a .csv or .json file"},
+		},
+		Examples: []string{
+			"bench prompts.txt llama3 mistral",
+			"bench -n 5 -unload -export results.csv prompts.txt llama3 qwen3",
+		},
+	},
+	"Blocks": {
+		Details: "Lists the fenced code blocks in the last generate or chat answer. A block can be " +
+			"saved to a file, piped to a shell command, or copied to the clipboard through the " +
+			"terminal with OSC 52.",
+		Examples: []string{"blocks", "blocks save 1 main.go", "blocks pipe 2 python3", "blocks copy 1"},
+	},
+	"Chat": {
+		Details: "Sends a message along with the history of the conversation. The model can be " +
+			"left off once the conversation has one, or the profile gives one. @file:, @dir: and @img: in the prompt add " +
+			"files and images.",
+		Flags: []actionFlag{formatFlag},
+		Examples: []string{
+			"chat llama3 user Why is the sky blue?",
+			"chat user Explain it like I am five",
+			"chat -format json llama3 user List three colors",
+		},
+	},
+	"Conversation": {
+		Details:  "Starts a new conversation, shows the history, takes back the last turn, or changes the model.",
+		Examples: []string{"conversation show", "conversation new llama3", "conv undo", "conv model mistral"},
+	},
+	"Copy": {
+		Details:  "Copies a model to a new name on the server.",
+		Examples: []string{"cp llama3 my-llama3"},
+	},
+	"Create": {
+		Details:  "Creates a model from a Modelfile, uploading any local files it uses.",
+		Examples: []string{"create my-model ./Modelfile"},
+	},
+	"Delete": {
+		Details:  "Deletes a model from the server after asking first.",
+		Flags:    []actionFlag{{"force", "", "delete without asking first"}},
+		Examples: []string{"rm my-model", "rm -force my-model"},
+	},
+	"Edit": {
+		Details:  "Opens $VISUAL or $EDITOR and sends what is written as a user message to the model of the conversation.",
+		Examples: []string{"edit", "edit llama3"},
+	},
+	"Embed": {
+		Details: "Gets embedding vectors for text, or for each line of a file. With similarity, " +
+			"compares two pieces of text separated by |. When the profile gives a model every word " +
+			"is text, use -model for another model.",
+		Flags: []actionFlag{
+			{"format", "f", "output as json, csv or summary"},
+			{"n", "count", "number of values to show in the summary"},
+			{"file", "path", "embed each line of a file instead of the text"},
+			modelFlag,
+		},
+		Examples: []string{
+			"embed nomic-embed-text The sky is blue",
+			"embed -format csv -file lines.txt nomic-embed-text",
+			"embed similarity nomic-embed-text cats | dogs",
+		},
+	},
+	"Exit": {
+		Details: "Exits the application.",
+	},
+	"Format": {
+		Details:  "Sets the output format used by the commands which show data. With no arguments the current format is shown.",
+		Examples: []string{"format json", "format table"},
+	},
+	"Generate": {
+		Details: "Generates text from a prompt, continuing from the last generate. When the profile " +
+			"gives a model every word is the prompt, use -model for another model. @file:, @dir: and @img: in the prompt add files " +
+			"and images.",
+		Flags: []actionFlag{formatFlag, modelFlag},
+		Examples: []string{
+			"generate llama3 Why is the sky blue?",
+			"generate llama3 Review this code @file:main.go",
+			"generate -model mistral Why is the sky blue?",
+		},
+	},
+	"Help": {
+		Details:  "Shows the menu, or the detailed help for one command.",
+		Examples: []string{"help", "help chat"},
+	},
+	"List": {
+		Details: "Lists the models on the server.",
+	},
+	"Options": {
+		Details: "Lists the model options which can be set, with their current values.",
+	},
+	"Profile": {
+		Details:  "Lists the profiles from the configuration file, or switches to one.",
+		Examples: []string{"profile", "profile work"},
+	},
+	"Processes": {
+		Details: "Lists the models loaded in memory.",
+	},
+	"Pull": {
+		Details:  "Downloads a model from a registry, showing progress. Ctrl-C stops the download.",
+		Flags:    []actionFlag{{"insecure", "", "allow pulling from a registry without TLS"}},
+		Examples: []string{"pull llama3"},
+	},
+	"Push": {
+		Details:  "Uploads a model to a registry, showing progress.",
+		Flags:    []actionFlag{{"insecure", "", "allow pushing to a registry without TLS"}},
+		Examples: []string{"push me/my-model"},
+	},
+	"Render": {
+		Details: "Sets how generate and chat print answers, either the text as it arrives or with the " +
+			"Markdown rendered. Markdown is the default when run interactively.",
+		Examples: []string{"render raw", "render markdown"},
+	},
+	"Session": {
+		Details:  "Saves the conversation, context and options to a named session, or restores one.",
+		Examples: []string{"session save work", "session load work", "session list", "session delete work"},
+	},
+	"Set": {
+		Details:  "Sets a model option sent with each request. Run options to see them all.",
+		Examples: []string{"set temperature 0.2", "set stop END ###"},
+	},
+	"Show": {
+		Details:  "Shows the details of a model, or of the model from the profile.",
+		Examples: []string{"show llama3"},
+	},
+	"Stats": {
+		Details: "Shows the token counts, speeds and times for all the answers so far. on or off sets " +
+			"whether a footer with the same for each answer follows it, which is on when run " +
+			"interactively. reset starts the totals again.",
+		Examples: []string{"stats", "stats off", "stats reset"},
+	},
+	"Tools": {
+		Details:  "Manages the tools a model may call during a chat, either built in or loaded from a file.",
+		Examples: []string{"tools", "tools add calculator", "tools add all", "tools load tools.json", "tools remove calculator"},
+	},
+	"Unset": {
+		Details:  "Removes a model option so the model default is used.",
+		Examples: []string{"unset temperature", "unset all"},
+	},
+	"Version": {
+		Details: "Shows the version of the server.",
+	},
+}
+
+// Usage returns how the action is called
+func (a ActionableItem) Usage() string {
+	return strings.TrimSpace(a.Triggers[0] + " " + a.Parameters)
+}
+
+// CheckArgs makes sure there are as many arguments as the parameters call for. Leading flags are
+// skipped, along with their values.
+func (a ActionableItem) CheckArgs(args []string) error {
+	args = skipFlags(args, actionDetails[a.Name].Flags)
+	least, most := countParameters(a.Parameters)
+	switch {
+	case len(args) < least:
+		return fmt.Errorf("%s needs at least %d argument(s). Usage: %s", a.Triggers[0], least, a.Usage())
+	case most >= 0 && len(args) > most:
+		return fmt.Errorf("%s takes at most %d argument(s). Usage: %s", a.Triggers[0], most, a.Usage())
+	}
+	return nil
+}
+
+// countParameters works out the least and most arguments from a usage like
+// "[-force] <model> [name] <prompt...>". Flags are left out of the count, words in <> or without
+// brackets are required, those in [] are optional, and "..." means any number. most is -1 when
+// there is no limit.
+func countParameters(parameters string) (least, most int) {
+	for _, parameter := range splitParameters(parameters) {
+		if strings.HasPrefix(parameter, "[-") {
+			continue
+		}
+		if strings.Contains(parameter, "...") {
+			most = -1
+		} else if most >= 0 {
+			most++
+		}
+		if !strings.HasPrefix(parameter, "[") {
+			least++
+		}
+	}
+	return least, most
+}
+
+// splitParameters splits a usage on spaces, except those inside <> or []
+func splitParameters(parameters string) []string {
+	var parts []string
+	var part strings.Builder
+	depth := 0
+	for _, r := range parameters {
+		switch {
+		case r == '[' || r == '<':
+			depth++
+		case r == ']' || r == '>':
+			depth--
+		case r == ' ' && depth == 0:
+			if part.Len() > 0 {
+				parts = append(parts, part.String())
+				part.Reset()
+			}
+			continue
+		}
+		part.WriteRune(r)
+	}
+	if part.Len() > 0 {
+		parts = append(parts, part.String())
+	}
+	return parts
+}
+
+// skipFlags drops the leading flags, and any values they take, the same way the flag package
+// reads them
+func skipFlags(args []string, flags []actionFlag) []string {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+		if args[0] == "--" {
+			return args[1:]
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		flag := findFlag(flags, name)
+		if flag == nil {
+			return args
+		}
+		args = args[1:]
+		if flag.Value != "" && !hasValue && len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	return args
+}
+
+func findFlag(flags []actionFlag, name string) *actionFlag {
+	for i := range flags {
+		if flags[i].Name == name {
+			return &flags[i]
+		}
+	}
+	return nil
+}
+
+// writeActionHelp writes the detailed help for one action
+func writeActionHelp(out io.Writer, item ActionableItem) {
+	detail := actionDetails[item.Name]
+	fmt.Fprintln(out, strings.Repeat("*", 80))
+	fmt.Fprintf(out, "%s - %s\n\n", item.Name, item.Help)
+	fmt.Fprintf(out, "Usage: %s\n", item.Usage())
+	if len(item.Triggers) > 1 {
+		fmt.Fprintf(out, "Also: %s\n", strings.Join(item.Triggers[1:], ", "))
+	}
+	if detail.Details != "" {
+		fmt.Fprintf(out, "\n%s\n", detail.Details)
+	}
+	if len(detail.Flags) > 0 {
+		fmt.Fprintln(out, "\nFlags:")
+		for _, flag := range detail.Flags {
+			fmt.Fprintf(out, "  %-16s %s\n", strings.TrimSpace("-"+flag.Name+" "+flag.Value), flag.Help)
+		}
+	}
+	if len(detail.Examples) > 0 {
+		fmt.Fprintln(out, "\nExamples:")
+		for _, example := range detail.Examples {
+			fmt.Fprintf(out, "  %s\n", example)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// **********************************************************************************************100
+/*
+Tests for reading the usage of an action: counting the arguments it takes, skipping its flags, and
+checking that every flag in a usage is described in the help.
+
+Created by Thomas.Cherry.gmail.com
+*/
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitParameters(t *testing.T) {
+	tests := []struct {
+		parameters string
+		want       []string
+	}{
+		{"", nil},
+		{"<model>", []string{"<model>"}},
+		{"[-n runs] <model>  [name]", []string{"[-n runs]", "<model>", "[name]"}},
+		{"[clear|<image>...]", []string{"[clear|<image>...]"}},
+		{"<first text> | <second text>", []string{"<first text>", "|", "<second text>"}},
+	}
+	for _, test := range tests {
+		t.Run(test.parameters, func(t *testing.T) {
+			if got := splitParameters(test.parameters); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("splitParameters(%q) = %q, want %q", test.parameters, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCountParameters(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters string
+		least      int
+		most       int
+	}{
+		{"nothing", "", 0, 0},
+		{"required", "<model>", 1, 1},
+		{"bare word is required", "model", 1, 1},
+		{"two required", "<source> <destination>", 2, 2},
+		{"optional", "[name]", 0, 1},
+		{"required and optional", "<model> [name]", 1, 2},
+		{"variadic", "<prompt...>", 1, -1},
+		{"optional variadic", "[text...]", 0, -1},
+		{"optional before variadic", "[model] <prompt...>", 1, -1},
+		{"choice in brackets", "[clear|<image>...]", 0, -1},
+		{"nested brackets", "[<a> <b>]", 0, 1},
+		{"flag is not counted", "[-force] <model>", 1, 1},
+		{"flag with a value is not counted", "[-n runs] [-export file] <prompt-file> <model...>", 2, -1},
+		{"only flags", "[-a] [-b x]", 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			least, most := countParameters(test.parameters)
+			if least != test.least || most != test.most {
+				t.Errorf("countParameters(%q) = %d, %d, want %d, %d",
+					test.parameters, least, most, test.least, test.most)
+			}
+		})
+	}
+}
+
+func TestSkipFlags(t *testing.T) {
+	flags := []actionFlag{{"force", "", "no value"}, {"n", "runs", "takes a value"}}
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"no flags", []string{"a", "b"}, []string{"a", "b"}},
+		{"no args", []string{}, []string{}},
+		{"switch", []string{"-force", "a"}, []string{"a"}},
+		{"double dash switch", []string{"--force", "a"}, []string{"a"}},
+		{"value in the next word", []string{"-n", "3", "a"}, []string{"a"}},
+		{"value after equals", []string{"-n=3", "a"}, []string{"a"}},
+		{"switch with equals", []string{"-force=true", "a"}, []string{"a"}},
+		{"several flags", []string{"-force", "-n", "3", "a", "b"}, []string{"a", "b"}},
+		{"value missing at the end", []string{"-n"}, []string{}},
+		{"end of flags", []string{"-force", "--", "-n", "a"}, []string{"-n", "a"}},
+		{"unknown flag stops", []string{"-other", "a"}, []string{"-other", "a"}},
+		{"flags after words are kept", []string{"a", "-force"}, []string{"a", "-force"}},
+		{"lone dash is a word", []string{"-", "a"}, []string{"-", "a"}},
+		{"negative number is not a flag", []string{"-1", "a"}, []string{"-1", "a"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := skipFlags(test.args, flags); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("skipFlags(%q) = %q, want %q", test.args, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckArgs(t *testing.T) {
+	item := ActionableItem{Name: "Delete", Triggers: []string{"rm"}, Parameters: "[-force] <model>"}
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"enough", []string{"llama3"}, ""},
+		{"flag and model", []string{"-force", "llama3"}, ""},
+		{"missing", []string{}, "rm needs at least 1 argument(s). Usage: rm [-force] <model>"},
+		{"only a flag", []string{"-force"}, "rm needs at least 1 argument(s). Usage: rm [-force] <model>"},
+		{"too many", []string{"a", "b"}, "rm takes at most 1 argument(s). Usage: rm [-force] <model>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ""
+			if err := item.CheckArgs(test.args); err != nil {
+				got = err.Error()
+			}
+			if got != test.want {
+				t.Errorf("CheckArgs(%q) = %q, want %q", test.args, got, test.want)
+			}
+		})
+	}
+}
+
+// TestActionFlagsDescribed makes sure each flag in a usage has help, which is also what lets
+// CheckArgs skip it
+func TestActionFlagsDescribed(t *testing.T) {
+	for _, item := range actions {
+		for _, parameter := range splitParameters(item.Parameters) {
+			if !strings.HasPrefix(parameter, "[-") {
+				continue
+			}
+			name, value, _ := strings.Cut(strings.Trim(parameter, "[]-"), " ")
+			flag := findFlag(actionDetails[item.Name].Flags, name)
+			if flag == nil {
+				t.Errorf("%s: flag -%s has no help", item.Name, name)
+			} else if (flag.Value == "") != (value == "") {
+				t.Errorf("%s: flag -%s takes %q in the usage but %q in the help", item.Name, name, value, flag.Value)
+			}
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// **********************************************************************************************100
+/*
+A small calculator for arithmetic expressions, like "2 * (3 + 4) ^ 2" or "sqrt(2) / 2".
+
+created by Thomas.Cherry.gmail.com
+*/
+
+package lib
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var calcConstants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+var calcFunctions = map[string]func(float64) float64{
+	"abs":   math.Abs,
+	"sqrt":  math.Sqrt,
+	"cbrt":  math.Cbrt,
+	"exp":   math.Exp,
+	"ln":    math.Log,
+	"log":   math.Log10,
+	"log2":  math.Log2,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"asin":  math.Asin,
+	"acos":  math.Acos,
+	"atan":  math.Atan,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"round": math.Round,
+}
+
+// calculator is a recursive descent parser which works out the value as it reads
+type calculator struct {
+	text     string
+	position int
+}
+
+// Calculate evaluates an expression with + - * / % ^, parentheses, the constants pi and e, and
+// functions like sqrt(x).
+func Calculate(expression string) (float64, error) {
+	calc := &calculator{text: expression}
+	value, err := calc.sum()
+	if err != nil {
+		return 0, err
+	}
+	calc.skipSpace()
+	if calc.position < len(calc.text) {
+		return 0, fmt.Errorf("unexpected [%s] at position %d", calc.text[calc.position:], calc.position+1)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("result is not a number")
+	}
+	return value, nil
+}
+
+func (c *calculator) skipSpace() {
+	for c.position < len(c.text) && c.text[c.position] == ' ' {
+		c.position++
+	}
+}
+
+// next returns the next character without moving past it, zero at the end
+func (c *calculator) next() byte {
+	c.skipSpace()
+	if c.position < len(c.text) {
+		return c.text[c.position]
+	}
+	return 0
+}
+
+// sum = product { ("+" | "-") product }
+func (c *calculator) sum() (float64, error) {
+	value, err := c.product()
+	for err == nil {
+		operator := c.next()
+		if operator != '+' && operator != '-' {
+			break
+		}
+		c.position++
+		var right float64
+		if right, err = c.product(); err == nil {
+			if operator == '+' {
+				value += right
+			} else {
+				value -= right
+			}
+		}
+	}
+	return value, err
+}
+
+// product = unary { ("*" | "/" | "%") unary }
+func (c *calculator) product() (float64, error) {
+	value, err := c.unary()
+	for err == nil {
+		operator := c.next()
+		if operator != '*' && operator != '/' && operator != '%' {
+			break
+		}
+		c.position++
+		var right float64
+		if right, err = c.unary(); err != nil {
+			break
+		}
+		switch {
+		case operator == '*':
+			value *= right
+		case right == 0:
+			err = fmt.Errorf("division by zero")
+		case operator == '/':
+			value /= right
+		default:
+			value = math.Mod(value, right)
+		}
+	}
+	return value, err
+}
+
+// unary = ("-" | "+") unary | power, so -2^2 is -(2^2)
+func (c *calculator) unary() (float64, error) {
+	switch c.next() {
+	case '-':
+		c.position++
+		value, err := c.unary()
+		return -value, err
+	case '+':
+		c.position++
+		return c.unary()
+	}
+	return c.power()
+}
+
+// power = primary [ "^" unary ], right associative
+func (c *calculator) power() (float64, error) {
+	base, err := c.primary()
+	if err != nil || c.next() != '^' {
+		return base, err
+	}
+	c.position++
+	exponent, err := c.unary()
+	return math.Pow(base, exponent), err
+}
+
+// primary = number | "(" sum ")" | name [ "(" sum ")" ]
+func (c *calculator) primary() (float64, error) {
+	next := c.next()
+	switch {
+	case next == '(':
+		c.position++
+		value, err := c.sum()
+		if err != nil {
+			return 0, err
+		}
+		if c.next() != ')' {
+			return 0, fmt.Errorf("missing ) at position %d", c.position+1)
+		}
+		c.position++
+		return value, nil
+	case next == '.' || unicode.IsDigit(rune(next)):
+		start := c.position
+		for c.position < len(c.text) && strings.ContainsRune("0123456789._eE", rune(c.text[c.position])) {
+			// an exponent may have a sign, like 1e-3
+			if (c.text[c.position] == 'e' || c.text[c.position] == 'E') && c.position+1 < len(c.text) &&
+				strings.ContainsRune("+-", rune(c.text[c.position+1])) {
+				c.position++
+			}
+			c.position++
+		}
+		value, err := strconv.ParseFloat(strings.ReplaceAll(c.text[start:c.position], "_", ""), 64)
+		if err != nil {
+			return 0, fmt.Errorf("bad number [%s]", c.text[start:c.position])
+		}
+		return value, nil
+	case next < utf8.RuneSelf && unicode.IsLetter(rune(next)):
+		start := c.position
+		for c.position < len(c.text) && c.text[c.position] < utf8.RuneSelf &&
+			(unicode.IsLetter(rune(c.text[c.position])) || unicode.IsDigit(rune(c.text[c.position]))) {
+			c.position++
+		}
+		name := strings.ToLower(c.text[start:c.position])
+		if value, found := calcConstants[name]; found {
+			return value, nil
+		}
+		function, found := calcFunctions[name]
+		if !found {
+			return 0, fmt.Errorf("unknown name [%s]", name)
+		}
+		if c.next() != '(' {
+			return 0, fmt.Errorf("%s needs an argument in ( )", name)
+		}
+		value, err := c.primary()
+		return function(value), err
+	case next == 0:
+		return 0, fmt.Errorf("expression ended early")
+	}
+	unexpected, _ := utf8.DecodeRuneInString(c.text[c.position:])
+	return 0, fmt.Errorf("unexpected [%c] at position %d", unexpected, c.position+1)
+}
@@ -0,0 +1,117 @@
+// **********************************************************************************************100
+/*
+Tests for the calculator: precedence, unary minus, functions and constants, and the errors for
+division by zero and expressions which can not be read.
+
+created by Thomas.Cherry.gmail.com
+*/
+
+package lib
+
+import (
+	"math"
+	"testing"
+)
+
+// calculate runs Calculate, turning a panic into a test failure so one bad case does not stop the rest
+func calculate(t *testing.T, expression string) (value float64, err error) {
+	t.Helper()
+	defer func() {
+		if problem := recover(); problem != nil {
+			t.Fatalf("Calculate(%q) panicked: %v", expression, problem)
+		}
+	}()
+	return Calculate(expression)
+}
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       float64
+	}{
+		{"number", "42", 42},
+		{"fraction", ".5", 0.5},
+		{"exponent", "1e-3", 0.001},
+		{"underscores", "1_000", 1000},
+		{"spaces", "  1 +   2  ", 3},
+
+		// precedence
+		{"product before sum", "2 + 3 * 4", 14},
+		{"parentheses first", "(2 + 3) * 4", 20},
+		{"left to right", "10 - 4 - 3", 3},
+		{"division left to right", "100 / 10 / 5", 2},
+		{"power before product", "2 * 3 ^ 2", 18},
+		{"power is right associative", "2 ^ 3 ^ 2", 512},
+		{"remainder", "7 % 4 + 1", 4},
+		{"nested parentheses", "((1 + 2) * (3 + 4))", 21},
+
+		// unary minus
+		{"negative number", "-5", -5},
+		{"minus a group", "-(2 + 3)", -5},
+		{"double minus", "--5", 5},
+		{"plus sign", "+5", 5},
+		{"minus after an operator", "3 * -2", -6},
+		{"minus before a power", "-2 ^ 2", -4},
+		{"negative exponent", "2 ^ -1", 0.5},
+
+		// names
+		{"pi", "pi", math.Pi},
+		{"names ignore case", "PI", math.Pi},
+		{"function", "sqrt(16)", 4},
+		{"function of an expression", "abs(1 - 3) * 2", 4},
+		{"nested functions", "floor(sqrt(10))", 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := calculate(t, test.expression)
+			if err != nil {
+				t.Fatalf("Calculate(%q) failed: %v", test.expression, err)
+			}
+			if math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("Calculate(%q) = %v, want %v", test.expression, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCalculateErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       string
+	}{
+		{"division by zero", "1 / 0", "division by zero"},
+		{"division by a zero expression", "4 / (2 - 2)", "division by zero"},
+		{"remainder by zero", "5 % 0", "division by zero"},
+		{"not a number", "sqrt(-1)", "result is not a number"},
+		{"too large", "10 ^ 400", "result is not a number"},
+
+		// malformed
+		{"empty", "", "expression ended early"},
+		{"only spaces", "   ", "expression ended early"},
+		{"trailing operator", "1 +", "expression ended early"},
+		{"only a minus", "-", "expression ended early"},
+		{"missing close", "(1 + 2", "missing ) at position 7"},
+		{"extra close", "1 + 2)", "unexpected [)] at position 6"},
+		{"two numbers", "1 2", "unexpected [2] at position 3"},
+		{"double operator", "1 * * 2", "unexpected [*] at position 5"},
+		{"bad number", "1.2.3", "bad number [1.2.3]"},
+		{"unknown name", "foo + 1", "unknown name [foo]"},
+		{"function without parentheses", "sqrt 4", "sqrt needs an argument in ( )"},
+		{"empty function", "sqrt()", "unexpected [)] at position 6"},
+		{"other characters", "1 $ 2", "unexpected [$ 2] at position 3"},
+		{"non ascii", "1 + é", "unexpected [é] at position 5"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := calculate(t, test.expression)
+			if err == nil {
+				t.Fatalf("Calculate(%q) = %v, want the error %q", test.expression, got, test.want)
+			}
+			if err.Error() != test.want {
+				t.Errorf("Calculate(%q) error = %q, want %q", test.expression, err, test.want)
+			}
+		})
+	}
+}
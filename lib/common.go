@@ -11,6 +11,8 @@ package lib
 import (
 	"encoding/json"
 	"os"
+	"unicode"
+	"unicode/utf8"
 )
 
 // action function
@@ -42,3 +44,15 @@ func JsonFromStruct[K any](data K) ([]byte, error) {
 	result, err := json.Marshal(data)
 	return result, err
 }
+
+/**************************************/
+// MARK: - Text functions
+
+// Capitalize returns text with its first letter in upper case
+func Capitalize(text string) string {
+	first, size := utf8.DecodeRuneInString(text)
+	if first == utf8.RuneError {
+		return text // empty, or not valid UTF-8 to begin with
+	}
+	return string(unicode.ToUpper(first)) + text[size:]
+}
@@ -68,6 +68,9 @@ const (
 
 type Codes []Code
 
+// ColorEnabled turns the codes added by WrapText on or off, NO_COLOR in the environment turns it off
+var ColorEnabled = os.Getenv("NO_COLOR") == ""
+
 // Strings returns a slice of strings representing the codes.
 func (c Codes) Strings() []string {
 	var strCodes []string
@@ -79,6 +82,9 @@ func (c Codes) Strings() []string {
 
 // WrapText wraps the given text with the specified ANSI color code.
 func WrapText(colorCodes Codes, text string) string {
+	if !ColorEnabled {
+		return text
+	}
 	codes := strings.Join(colorCodes.Strings(), ";")
 	offCodes := []string{}
 	for _, code := range colorCodes {
@@ -104,6 +110,34 @@ func WrapText(colorCodes Codes, text string) string {
 	return fmt.Sprintf("\033[%sm%s\033[%sm", codes, text, offCodesStr)
 }
 
+// Escape returns the control sequence for one of the terminal codes, like ESC_CLEAR_LINE
+func Escape(code string) string {
+	return "\033[" + code
+}
+
+// ProgressBar draws a bar of width characters filled in proportion to completed out of total.
+func ProgressBar(width int, completed, total int64) string {
+	filled := 0
+	if total > 0 {
+		filled = int(int64(width) * min(completed, total) / total)
+	}
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "]"
+}
+
+// HumanBytes formats a size in bytes using the largest unit which keeps the number above 1.
+func HumanBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
 /*
 ESC(0
 lqqqqk
@@ -0,0 +1,276 @@
+// **********************************************************************************************100
+/*
+Syntax highlighting for code blocks, a line at a time. A small lexer finds comments, strings,
+numbers, keywords and literals for a few languages; anything else is left as plain text.
+
+created by Thomas.Cherry.gmail.com
+*/
+
+package lib
+
+import (
+	"regexp"
+	"strings"
+)
+
+// syntax describes enough of a language to color it
+type syntax struct {
+	keywords      []string
+	literals      []string // constants like true and nil
+	lineComments  []string
+	blockComment  [2]string
+	quotes        string   // characters which start a string on one line
+	longStrings   []string // delimiters of strings which may span lines
+	ignoreCase    bool
+	variables     bool // shell style $NAME and ${NAME}
+	keys          bool // color "key": in JSON
+	yamlKeys      bool // color key: at the start of YAML lines
+	hashAfterWord bool // a # inside a word, like a#b, does not start a comment
+}
+
+var (
+	syntaxGo = &syntax{
+		keywords: strings.Fields(`break case chan const continue default defer else fallthrough for
+			func go goto if import interface map package range return select struct switch type var`),
+		literals: strings.Fields(`true false nil iota any bool byte error int int8 int16 int32 int64
+			uint uint8 uint16 uint32 uint64 uintptr float32 float64 complex64 complex128 rune string
+			append cap close delete len make new panic print println recover`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"'`,
+		longStrings:  []string{"`"},
+	}
+	syntaxPython = &syntax{
+		keywords: strings.Fields(`and as assert async await break class continue def del elif else
+			except finally for from global if import in is lambda nonlocal not or pass raise return
+			try while with yield match case`),
+		literals: strings.Fields(`True False None self print len range str int float list dict set
+			tuple open`),
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		longStrings:  []string{`"""`, `'''`},
+	}
+	syntaxJavaScript = &syntax{
+		keywords: strings.Fields(`async await break case catch class const continue debugger default
+			delete do else export extends finally for from function if import in instanceof let new of
+			return static super switch this throw try typeof var void while with yield interface type
+			enum implements`),
+		literals:     strings.Fields(`true false null undefined NaN Infinity console`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"'`,
+		longStrings:  []string{"`"},
+	}
+	syntaxJSON = &syntax{
+		literals: strings.Fields(`true false null`),
+		quotes:   `"`,
+		keys:     true,
+	}
+	syntaxYAML = &syntax{
+		literals:      strings.Fields(`true false null yes no on off`),
+		lineComments:  []string{"#"},
+		quotes:        `"'`,
+		yamlKeys:      true,
+		hashAfterWord: true,
+	}
+	syntaxSQL = &syntax{
+		keywords: strings.Fields(`add all alter and as asc begin between by case check column commit
+			constraint create database default delete desc distinct drop else end exists foreign from
+			full group having if in index inner insert into is join key left like limit not null offset
+			on or order outer primary references returning right rollback select set table then to
+			transaction union unique update using values view when where with`),
+		literals: strings.Fields(`true false count sum avg min max coalesce now int integer bigint
+			text varchar char boolean date timestamp serial numeric real`),
+		lineComments: []string{"--"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `'"`,
+		ignoreCase:   true,
+	}
+	syntaxShell = &syntax{
+		keywords: strings.Fields(`if then else elif fi for while until do done case esac in function
+			select return exit export local readonly unset shift break continue`),
+		literals:      strings.Fields(`echo cd printf read source test true false set eval exec trap`),
+		lineComments:  []string{"#"},
+		quotes:        `"'`,
+		variables:     true,
+		hashAfterWord: true,
+	}
+)
+
+// syntaxes maps the language names used on fenced code blocks to their syntax
+var syntaxes = map[string]*syntax{
+	"go": syntaxGo, "golang": syntaxGo,
+	"python": syntaxPython, "py": syntaxPython, "python3": syntaxPython,
+	"javascript": syntaxJavaScript, "js": syntaxJavaScript, "jsx": syntaxJavaScript,
+	"typescript": syntaxJavaScript, "ts": syntaxJavaScript, "tsx": syntaxJavaScript,
+	"json": syntaxJSON, "jsonc": syntaxJSON,
+	"yaml": syntaxYAML, "yml": syntaxYAML,
+	"sql": syntaxSQL, "postgresql": syntaxSQL, "mysql": syntaxSQL, "sqlite": syntaxSQL,
+	"sh": syntaxShell, "bash": syntaxShell, "shell": syntaxShell, "zsh": syntaxShell,
+	"console": syntaxShell,
+}
+
+var (
+	yamlKeyPattern = regexp.MustCompile(`^(\s*(?:-\s+)?)([^\s#'"{\[][^:#]*?|"[^"]*"|'[^']*')(\s*:)(\s|$)`)
+	numberPattern  = regexp.MustCompile(`^(0[xXbBoO][0-9a-fA-F_]+|[0-9][0-9_]*(\.[0-9_]+)?([eE][-+]?[0-9]+)?)`)
+)
+
+// Highlighter colors the lines of one code block, remembering comments and strings which carry on
+// to the next line
+type Highlighter struct {
+	syntax *syntax
+	words  map[string]Codes
+	open   string // the delimiter which will end the comment or string carried over, if any
+	color  Code
+}
+
+// NewHighlighter returns a highlighter for a language, or nil if the language is not known
+func NewHighlighter(language string) *Highlighter {
+	s, found := syntaxes[strings.ToLower(language)]
+	if !found {
+		return nil
+	}
+	words := map[string]Codes{}
+	for _, word := range s.literals {
+		words[word] = Codes{ESC_CYAN}
+	}
+	for _, word := range s.keywords {
+		words[word] = Codes{ESC_MAGENTA}
+	}
+	return &Highlighter{syntax: s, words: words}
+}
+
+// Line returns one line of code with color codes added
+func (h *Highlighter) Line(line string) string {
+	if !ColorEnabled {
+		return line
+	}
+	var sb strings.Builder
+	i := 0
+	if h.open != "" {
+		i = h.finish(&sb, line, 0, 0)
+	}
+	if h.syntax.yamlKeys && i == 0 {
+		if match := yamlKeyPattern.FindStringSubmatchIndex(line); match != nil {
+			sb.WriteString(line[:match[3]])
+			sb.WriteString(WrapText(Codes{ESC_BLUE}, line[match[4]:match[5]]))
+			sb.WriteString(line[match[5]:match[7]])
+			i = match[7]
+		}
+	}
+
+	s := h.syntax
+	for i < len(line) {
+		rest := line[i:]
+		if prefix := hasAnyPrefix(rest, s.lineComments); prefix != "" &&
+			!(s.hashAfterWord && prefix == "#" && i > 0 && line[i-1] != ' ' && line[i-1] != '\t') {
+			sb.WriteString(WrapText(Codes{ESC_FAINT}, rest))
+			break
+		}
+		if s.blockComment[0] != "" && strings.HasPrefix(rest, s.blockComment[0]) {
+			h.open, h.color = s.blockComment[1], ESC_FAINT
+			i = h.finish(&sb, line, i, len(s.blockComment[0]))
+			continue
+		}
+		if delimiter := hasAnyPrefix(rest, s.longStrings); delimiter != "" {
+			h.open, h.color = delimiter, ESC_GREEN
+			i = h.finish(&sb, line, i, len(delimiter))
+			continue
+		}
+		c := line[i]
+		switch {
+		case strings.IndexByte(s.quotes, c) >= 0:
+			end := closingQuote(line, i)
+			text := line[i:end]
+			color := Codes{ESC_GREEN}
+			if s.keys && strings.HasPrefix(strings.TrimLeft(line[end:], " \t"), ":") {
+				color = Codes{ESC_BLUE}
+			}
+			sb.WriteString(WrapText(color, text))
+			i = end
+		case s.variables && c == '$' && i+1 < len(line):
+			end := i + 1
+			if line[end] == '{' {
+				if close := strings.IndexByte(line[end:], '}'); close > 0 {
+					end += close + 1
+				}
+			} else {
+				for end < len(line) && (isWordByte(line[end]) || (end == i+1 && strings.IndexByte("?#@*!$", line[end]) >= 0)) {
+					end++
+				}
+			}
+			sb.WriteString(WrapText(Codes{ESC_CYAN}, line[i:end]))
+			i = end
+		case c >= '0' && c <= '9' && (i == 0 || !isWordByte(line[i-1])):
+			number := numberPattern.FindString(rest)
+			if len(number) > 0 && (i+len(number) == len(line) || !isWordByte(line[i+len(number)])) {
+				sb.WriteString(WrapText(Codes{ESC_YELLOW}, number))
+				i += len(number)
+			} else {
+				sb.WriteByte(c)
+				i++
+			}
+		case isWordByte(c):
+			end := i
+			for end < len(line) && isWordByte(line[end]) {
+				end++
+			}
+			word := line[i:end]
+			lookup := word
+			if s.ignoreCase {
+				lookup = strings.ToLower(word)
+			}
+			if codes, found := h.words[lookup]; found {
+				sb.WriteString(WrapText(codes, word))
+			} else {
+				sb.WriteString(word)
+			}
+			i = end
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// finish writes a comment or string from start, skipping the mark which opened it, returning where
+// the text after it begins. The whole line is used if it does not end here, and carries on to the
+// next line.
+func (h *Highlighter) finish(sb *strings.Builder, line string, start, skip int) int {
+	end := strings.Index(line[start+skip:], h.open)
+	if end < 0 {
+		if start < len(line) {
+			sb.WriteString(WrapText(Codes{h.color}, line[start:]))
+		}
+		return len(line)
+	}
+	end += start + skip + len(h.open)
+	sb.WriteString(WrapText(Codes{h.color}, line[start:end]))
+	h.open = ""
+	return end
+}
+
+// closingQuote returns the position after the quote which ends the string starting at start, or
+// the end of the line if it is not closed
+func closingQuote(line string, start int) int {
+	quote := line[start]
+	for i := start + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return len(line)
+}
+
+func hasAnyPrefix(text string, prefixes []string) string {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(text, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+// **********************************************************************************************100
+/*
+Tests for the code highlighter: keywords, literals, numbers, strings and comments, including those
+which carry on to the next line, and languages which are not known.
+
+created by Thomas.Cherry.gmail.com
+*/
+
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+// the colors the highlighter gives each kind of text
+func kw(text string) string  { return WrapText(Codes{ESC_MAGENTA}, text) }
+func lit(text string) string { return WrapText(Codes{ESC_CYAN}, text) }
+func str(text string) string { return WrapText(Codes{ESC_GREEN}, text) }
+func com(text string) string { return WrapText(Codes{ESC_FAINT}, text) }
+func num(text string) string { return WrapText(Codes{ESC_YELLOW}, text) }
+func key(text string) string { return WrapText(Codes{ESC_BLUE}, text) }
+
+func TestHighlighter(t *testing.T) {
+	saved := ColorEnabled
+	ColorEnabled = true
+	defer func() { ColorEnabled = saved }()
+
+	tests := []struct {
+		name     string
+		language string
+		lines    []string
+		want     []string
+	}{
+		{"plain words", "go", []string{"x = y"}, []string{"x = y"}},
+		{"language ignores case", "Go", []string{"if"}, []string{kw("if")}},
+
+		// keywords and literals
+		{"go keywords", "go", []string{"func main() {"}, []string{kw("func") + " main() {"}},
+		{"go literals", "go", []string{"return nil, true"}, []string{kw("return") + " " + lit("nil") + ", " + lit("true")}},
+		{"keyword inside a word", "go", []string{"format iffy"}, []string{"format iffy"}},
+		{"python keywords", "python", []string{"def f(): pass"}, []string{kw("def") + " f(): " + kw("pass")}},
+		{"sql ignores case", "sql", []string{"SELECT x FROM t"}, []string{kw("SELECT") + " x " + kw("FROM") + " t"}},
+		{"shell variables", "sh", []string{"echo $HOME ${A}"}, []string{lit("echo") + " " + lit("$HOME") + " " + lit("${A}")}},
+
+		// numbers
+		{"numbers", "go", []string{"x := 42 + 0x1F + 1.5e3"}, []string{"x := " + num("42") + " + " + num("0x1F") + " + " + num("1.5e3")}},
+		{"digits in a name", "go", []string{"int64 v2"}, []string{lit("int64") + " v2"}},
+
+		// strings
+		{"double quotes", "go", []string{`s := "if nil"`}, []string{"s := " + str(`"if nil"`)}},
+		{"escaped quote", "go", []string{`"a\"b" + c`}, []string{str(`"a\"b"`) + " + c"}},
+		{"unclosed string", "python", []string{`x = 'abc`}, []string{"x = " + str(`'abc`)}},
+		{"comment mark in a string", "go", []string{`"// no"`}, []string{str(`"// no"`)}},
+		{"raw string over lines", "go", []string{"s := `one", "two` + x"},
+			[]string{"s := " + str("`one"), str("two`") + " + x"}},
+		{"python long string", "python", []string{`x = """a`, `b""" if`}, []string{"x = " + str(`"""a`), str(`b"""`) + " " + kw("if")}},
+		{"json keys", "json", []string{`{"a": "b", "c" : true}`},
+			[]string{"{" + key(`"a"`) + ": " + str(`"b"`) + ", " + key(`"c"`) + " : " + lit("true") + "}"}},
+		{"yaml keys", "yaml", []string{"name: x", "- item: yes"}, []string{key("name") + ": x", "- " + key("item") + ": " + lit("yes")}},
+
+		// comments
+		{"line comment", "go", []string{"x // if nil"}, []string{"x " + com("// if nil")}},
+		{"hash comment", "python", []string{"# if"}, []string{com("# if")}},
+		{"sql comment", "sql", []string{"select -- all"}, []string{kw("select") + " " + com("-- all")}},
+		{"block comment", "go", []string{"/* if */ if"}, []string{com("/* if */") + " " + kw("if")}},
+		{"block comment over lines", "js", []string{"a /* one", "two", "three */ let"},
+			[]string{"a " + com("/* one"), com("two"), com("three */") + " " + kw("let")}},
+		{"hash inside a word", "sh", []string{"echo a#b # c"}, []string{lit("echo") + " a#b " + com("# c")}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			highlighter := NewHighlighter(test.language)
+			if highlighter == nil {
+				t.Fatalf("NewHighlighter(%q) = nil, want a highlighter", test.language)
+			}
+			var got []string
+			for _, line := range test.lines {
+				got = append(got, highlighter.Line(line))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Line(%q) = %q, want %q", test.lines, got, test.want)
+			}
+		})
+	}
+}
+
+func TestHighlighterUnknownLanguage(t *testing.T) {
+	for _, language := range []string{"", "cobol", "text", "go2"} {
+		if highlighter := NewHighlighter(language); highlighter != nil {
+			t.Errorf("NewHighlighter(%q) = %v, want nil", language, highlighter)
+		}
+	}
+}
+
+func TestHighlighterNoColor(t *testing.T) {
+	saved := ColorEnabled
+	ColorEnabled = false
+	defer func() { ColorEnabled = saved }()
+
+	line := `func f() { return "x" } // done`
+	if got := NewHighlighter("go").Line(line); got != line {
+		t.Errorf("Line(%q) without color = %q, want it unchanged", line, got)
+	}
+}
@@ -0,0 +1,140 @@
+// **********************************************************************************************100
+/*
+A shell like lexer for splitting a line of input into commands and words. It understands single
+and double quotes, backslash escapes, commands separated by ";", newlines or "&&", and comments
+starting with "#":
+
+	gen llama3 "a; b"        one command, the prompt is a; b
+	gen llama3 it\'s         the prompt is it's
+	pull llama3 && ps        ps only runs if the pull worked
+	ls # list the models     the comment is ignored
+
+created by Thomas.Cherry.gmail.com
+*/
+
+package lib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Command is one command from a line of input
+type Command struct {
+	Words     []string
+	IfSuccess bool // joined to the command before it with "&&", only run when that one worked
+}
+
+// ParseCommands splits a line into commands and each command into words. Empty commands are left
+// out, except around "&&" where they are an error, as are unclosed quotes and a "\" at the very end.
+func ParseCommands(line string) ([]Command, error) {
+	var commands []Command
+	var current Command
+	var word strings.Builder
+	inWord := false // a word has been started, even an empty one like ""
+
+	endWord := func() {
+		if inWord {
+			current.Words = append(current.Words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(current.Words) > 0 {
+			commands = append(commands, current)
+		}
+		current = Command{}
+	}
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\':
+			i++
+			if i == len(runes) {
+				return nil, fmt.Errorf("nothing to escape after the \\ at %d", i)
+			}
+			if runes[i] != '\n' { // a backslash before a new line joins the lines
+				word.WriteRune(runes[i])
+			}
+			inWord = true
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("missing closing ' for the quote at %d", i+1)
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			i = end
+			inWord = true
+		case r == '"':
+			end, err := readDoubleQuoted(runes, i+1, &word)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			inWord = true
+		case r == '#' && !inWord:
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			i-- // let the new line end the command
+		case r == ';' || r == '\n':
+			endWord()
+			if current.IfSuccess && len(current.Words) == 0 {
+				return nil, fmt.Errorf("missing command after &&")
+			}
+			endCommand()
+		case r == '&' && i+1 < len(runes) && runes[i+1] == '&':
+			endWord()
+			if len(current.Words) == 0 {
+				return nil, fmt.Errorf("missing command before && at %d", i+1)
+			}
+			endCommand()
+			current.IfSuccess = true
+			i++
+		case r == ' ' || r == '\t' || r == '\r':
+			endWord()
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	endWord()
+	if current.IfSuccess && len(current.Words) == 0 {
+		return nil, fmt.Errorf("missing command after &&")
+	}
+	endCommand()
+	return commands, nil
+}
+
+// readDoubleQuoted copies the text of a double quoted string starting at start into word, returning
+// the position of the closing quote. Inside double quotes a backslash only escapes ", \ and a new
+// line, otherwise it is kept.
+func readDoubleQuoted(runes []rune, start int, word *strings.Builder) (int, error) {
+	for i := start; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == '"':
+			return i, nil
+		case r == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\\n", runes[i+1]):
+			i++
+			if runes[i] != '\n' {
+				word.WriteRune(runes[i])
+			}
+		default:
+			word.WriteRune(r)
+		}
+	}
+	return 0, fmt.Errorf("missing closing \" for the quote at %d", start)
+}
+
+func indexRune(runes []rune, start int, target rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == target {
+			return i
+		}
+	}
+	return -1
+}
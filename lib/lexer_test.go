@@ -0,0 +1,119 @@
+// **********************************************************************************************100
+/*
+Tests for the command lexer: quoting, escapes, command separators, comments and the errors for
+input which can not be read.
+
+created by Thomas.Cherry.gmail.com
+*/
+
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+// words makes a command from its words, then marks it as joined with && if ifSuccess is set
+func words(ifSuccess bool, w ...string) Command {
+	return Command{Words: w, IfSuccess: ifSuccess}
+}
+
+func TestParseCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Command
+	}{
+		{"plain words", "gen llama3  hi\tthere", []Command{words(false, "gen", "llama3", "hi", "there")}},
+		{"empty line", "", nil},
+		{"only spaces", "  \t ", nil},
+
+		// quotes
+		{"single quotes", "gen 'a; b' c", []Command{words(false, "gen", "a; b", "c")}},
+		{"double quotes", `gen "a && b" c`, []Command{words(false, "gen", "a && b", "c")}},
+		{"empty quotes", `set stop "" ''`, []Command{words(false, "set", "stop", "", "")}},
+		{"quotes inside a word", `a"b c"d'e f'`, []Command{words(false, "ab cde f")}},
+		{"double quote inside single", `say 'he said "hi"'`, []Command{words(false, "say", `he said "hi"`)}},
+		{"single quote inside double", `say "it's"`, []Command{words(false, "say", "it's")}},
+
+		// escapes
+		{"escaped quote", `gen it\'s`, []Command{words(false, "gen", "it's")}},
+		{"escaped space", `cat a\ b`, []Command{words(false, "cat", "a b")}},
+		{"escaped separators", `gen a\;b \&\& c`, []Command{words(false, "gen", "a;b", "&&", "c")}},
+		{"escaped backslash", `gen a\\b`, []Command{words(false, "gen", `a\b`)}},
+		{"escaped hash", `gen \#tag`, []Command{words(false, "gen", "#tag")}},
+		{"escape joins lines", "gen a\\\nb", []Command{words(false, "gen", "ab")}},
+		{"escapes in double quotes", `gen "say \"hi\" \\ ok"`, []Command{words(false, "gen", `say "hi" \ ok`)}},
+		{"other escapes kept in double quotes", `gen "a\nb\$"`, []Command{words(false, "gen", `a\nb\$`)}},
+		{"escaped new line in double quotes", "gen \"a\\\nb\"", []Command{words(false, "gen", "ab")}},
+		{"no escapes in single quotes", `gen 'a\'`, []Command{words(false, "gen", `a\`)}},
+
+		// separators
+		{"semicolon", "ls; ps", []Command{words(false, "ls"), words(false, "ps")}},
+		{"semicolon without spaces", "ls;ps", []Command{words(false, "ls"), words(false, "ps")}},
+		{"new line", "ls\nps", []Command{words(false, "ls"), words(false, "ps")}},
+		{"and", "pull a && ps", []Command{words(false, "pull", "a"), words(true, "ps")}},
+		{"and without spaces", "pull a&&ps", []Command{words(false, "pull", "a"), words(true, "ps")}},
+		{"mixed", "a; b && c; d", []Command{words(false, "a"), words(false, "b"), words(true, "c"), words(false, "d")}},
+		{"chained and", "a && b && c", []Command{words(false, "a"), words(true, "b"), words(true, "c")}},
+		{"single ampersand is a word", "gen a & b", []Command{words(false, "gen", "a", "&", "b")}},
+
+		// comments
+		{"comment after words", "ls # list the models", []Command{words(false, "ls")}},
+		{"comment ends at new line", "ls # list\nps", []Command{words(false, "ls"), words(false, "ps")}},
+		{"comment hides separators", "ls # a; b && c", []Command{words(false, "ls")}},
+		{"whole line comment", "# nothing here", nil},
+		{"hash inside a word", "gen a#b", []Command{words(false, "gen", "a#b")}},
+		{"hash in double quotes", `gen "# not a comment"`, []Command{words(false, "gen", "# not a comment")}},
+		{"hash in single quotes", `gen '# not a comment'`, []Command{words(false, "gen", "# not a comment")}},
+
+		// empty commands
+		{"double semicolon", ";;", nil},
+		{"empty command between", "a;; b", []Command{words(false, "a"), words(false, "b")}},
+		{"trailing semicolon", "a;", []Command{words(false, "a")}},
+		{"leading semicolon", "; a", []Command{words(false, "a")}},
+		{"blank lines", "\n\na\n\n", []Command{words(false, "a")}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseCommands(test.input)
+			if err != nil {
+				t.Fatalf("ParseCommands(%q) failed: %v", test.input, err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseCommands(%q) = %#v, want %#v", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseCommandsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"unclosed single quote", "gen 'abc", "missing closing ' for the quote at 5"},
+		{"unclosed double quote", `gen "abc`, `missing closing " for the quote at 5`},
+		{"escaped closing double quote", `gen "abc\"`, `missing closing " for the quote at 5`},
+		{"dangling escape", `gen abc\`, `nothing to escape after the \ at 8`},
+		{"trailing and", "ls &&", "missing command after &&"},
+		{"trailing and with spaces", "ls &&  ", "missing command after &&"},
+		{"leading and", "&& ls", "missing command before && at 1"},
+		{"and then semicolon", "ls && ; ps", "missing command after &&"},
+		{"semicolon then and", "ls ; && ps", "missing command before && at 6"},
+		{"double and", "ls && && ps", "missing command before && at 7"},
+		{"and then comment", "ls && # nothing", "missing command after &&"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseCommands(test.input)
+			if err == nil {
+				t.Fatalf("ParseCommands(%q) = %#v, want the error %q", test.input, got, test.want)
+			}
+			if err.Error() != test.want {
+				t.Errorf("ParseCommands(%q) error = %q, want %q", test.input, err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+// **********************************************************************************************100
+/*
+Renders Markdown for the terminal as it streams in. Text is handled a line at a time, except for
+paragraphs, list items, quotes and headings which are written a word at a time once any emphasis
+or code in them is closed. Code blocks are drawn with a border as their lines arrive, and tables
+are held until they end so the columns can be lined up.
+
+created by Thomas.Cherry.gmail.com
+*/
+
+package lib
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletPattern  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedPattern = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	quotePattern   = regexp.MustCompile(`^>\s?(.*)$`)
+	fencePattern   = regexp.MustCompile("^\\s*(`{3,}|~{3,})\\s*([^`\\s]*)")
+	rulePattern    = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	cellPattern    = regexp.MustCompile(`^\s*:?-+:?\s*$`)
+	escapePattern  = regexp.MustCompile("\033\\[[0-9;]*m")
+)
+
+// MarkdownRenderer writes Markdown to a terminal with styles in place of the markup. Write the
+// text as it arrives and call Flush at the end.
+type MarkdownRenderer struct {
+	out   io.Writer
+	width int
+
+	line    string       // the current line, less any part of it which has been written
+	started bool         // the start of the current line has been written
+	style   Codes        // style for the rest of the current line
+	fence   string       // the marks which opened the current code block, empty outside one
+	code    *Highlighter // colors the current code block, nil when its language is not known
+	table   [][]string
+}
+
+// NewMarkdownRenderer returns a renderer writing to out, drawing rules and borders width wide
+func NewMarkdownRenderer(out io.Writer, width int) *MarkdownRenderer {
+	return &MarkdownRenderer{out: out, width: width}
+}
+
+// Write takes the next piece of Markdown, writing whatever can be rendered so far
+func (r *MarkdownRenderer) Write(p []byte) (int, error) {
+	r.line += string(p)
+	for {
+		end := strings.IndexByte(r.line, '\n')
+		if end < 0 {
+			break
+		}
+		text := r.line[:end]
+		r.line = r.line[end+1:]
+		r.renderLine(text)
+	}
+	r.renderPartial()
+	return len(p), nil
+}
+
+// Flush writes anything held back, closing any open code block or table
+func (r *MarkdownRenderer) Flush() error {
+	if r.line != "" || r.started {
+		text := r.line
+		r.line = ""
+		r.renderLine(text)
+	}
+	r.flushTable()
+	if r.fence != "" {
+		r.fence = ""
+		fmt.Fprintln(r.out, WrapText(Codes{ESC_FAINT}, "└"+strings.Repeat("─", r.width-1)))
+	}
+	return nil
+}
+
+// renderLine writes one whole line
+func (r *MarkdownRenderer) renderLine(text string) {
+	if r.started {
+		r.started = false
+		fmt.Fprintln(r.out, styled(r.style, RenderInline(text)))
+		return
+	}
+
+	if r.fence != "" {
+		if closesFence(text, r.fence) {
+			r.fence = ""
+			fmt.Fprintln(r.out, WrapText(Codes{ESC_FAINT}, "└"+strings.Repeat("─", r.width-1)))
+			return
+		}
+		code := strings.ReplaceAll(text, "\t", "    ")
+		if r.code != nil {
+			code = r.code.Line(code)
+		}
+		fmt.Fprintln(r.out, WrapText(Codes{ESC_FAINT}, "│ ")+code)
+		return
+	}
+	if match := fencePattern.FindStringSubmatch(text); match != nil {
+		r.flushTable()
+		r.fence, r.code = match[1], NewHighlighter(match[2])
+		label := ""
+		if match[2] != "" {
+			label = " " + match[2] + " "
+		}
+		top := "┌─" + label + strings.Repeat("─", max(r.width-2-utf8.RuneCountInString(label), 0))
+		fmt.Fprintln(r.out, WrapText(Codes{ESC_FAINT}, top))
+		return
+	}
+	if strings.HasPrefix(strings.TrimSpace(text), "|") {
+		r.table = append(r.table, splitRow(text))
+		return
+	}
+	r.flushTable()
+
+	switch {
+	case strings.TrimSpace(text) == "":
+		fmt.Fprintln(r.out)
+	case rulePattern.MatchString(text):
+		fmt.Fprintln(r.out, WrapText(Codes{ESC_FAINT}, strings.Repeat("─", r.width)))
+	default:
+		rest, style := r.startLine(text)
+		fmt.Fprintln(r.out, styled(style, RenderInline(rest)))
+	}
+}
+
+// renderPartial writes as much of an unfinished line as is safe, so long paragraphs appear as they
+// are written instead of all at once
+func (r *MarkdownRenderer) renderPartial() {
+	if r.fence != "" || r.line == "" {
+		return
+	}
+	if !r.started {
+		trimmed := strings.TrimSpace(r.line)
+		// wait for the whole line when it may be a fence, table or rule, or the kind is not known yet
+		if !strings.Contains(trimmed, " ") || strings.HasPrefix(trimmed, "|") ||
+			strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") ||
+			rulePattern.MatchString(r.line) {
+			return
+		}
+		r.flushTable()
+		r.line, r.style = r.startLine(r.line)
+		r.started = true
+	}
+	if cut := safeCut(r.line); cut > 0 {
+		fmt.Fprint(r.out, styled(r.style, RenderInline(r.line[:cut])))
+		r.line = r.line[cut:]
+	}
+}
+
+// startLine writes the marker for a heading, list item or quote, returning the rest of the text
+// and the style it should have
+func (r *MarkdownRenderer) startLine(text string) (string, Codes) {
+	if match := headingPattern.FindStringSubmatch(text); match != nil {
+		switch len(match[1]) {
+		case 1:
+			return match[2], Codes{ESC_BOLD, ESC_UNDERLINE, ESC_BLUE}
+		case 2:
+			return match[2], Codes{ESC_BOLD, ESC_BLUE}
+		}
+		return match[2], Codes{ESC_BOLD}
+	}
+	if match := bulletPattern.FindStringSubmatch(text); match != nil {
+		fmt.Fprint(r.out, match[1]+WrapText(Codes{ESC_YELLOW}, "•")+" ")
+		return match[2], nil
+	}
+	if match := orderedPattern.FindStringSubmatch(text); match != nil {
+		fmt.Fprint(r.out, match[1]+WrapText(Codes{ESC_YELLOW}, match[2])+" ")
+		return match[3], nil
+	}
+	if match := quotePattern.FindStringSubmatch(text); match != nil {
+		fmt.Fprint(r.out, WrapText(Codes{ESC_FAINT}, "│ "))
+		return match[1], Codes{ESC_ITALIC}
+	}
+	return text, nil
+}
+
+// flushTable writes the rows held back with the columns lined up
+func (r *MarkdownRenderer) flushTable() {
+	rows := r.table
+	r.table = nil
+	if len(rows) == 0 {
+		return
+	}
+
+	header := 0 // rows above the line of dashes
+	var body [][]string
+	for i, row := range rows {
+		if header == 0 && i > 0 && isSeparator(row) {
+			header = i
+			continue
+		}
+		rendered := make([]string, len(row))
+		for j, cell := range row {
+			rendered[j] = RenderInline(cell)
+		}
+		body = append(body, rendered)
+	}
+
+	widths := []int{}
+	for _, row := range body {
+		for j, cell := range row {
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[j] = max(widths[j], VisibleWidth(cell))
+		}
+	}
+	for i, row := range body {
+		var cells []string
+		for j, width := range widths {
+			cell := ""
+			if j < len(row) {
+				cell = row[j]
+			}
+			cell += strings.Repeat(" ", width-VisibleWidth(cell))
+			if i < header {
+				cell = WrapText(Codes{ESC_BOLD}, cell)
+			}
+			cells = append(cells, cell)
+		}
+		fmt.Fprintln(r.out, strings.Join(cells, WrapText(Codes{ESC_FAINT}, " │ ")))
+		if header > 0 && i == header-1 {
+			var rules []string
+			for _, width := range widths {
+				rules = append(rules, strings.Repeat("─", width))
+			}
+			fmt.Fprintln(r.out, WrapText(Codes{ESC_FAINT}, strings.Join(rules, "─┼─")))
+		}
+	}
+}
+
+// CodeBlock is a fenced block of code from a Markdown document
+type CodeBlock struct {
+	Language string
+	Code     string
+}
+
+// CodeBlocks returns the fenced code blocks in a Markdown document, including one left open at the
+// end
+func CodeBlocks(markdown string) []CodeBlock {
+	var blocks []CodeBlock
+	var lines []string
+	fence, language := "", ""
+	for _, line := range strings.Split(markdown, "\n") {
+		if fence == "" {
+			if match := fencePattern.FindStringSubmatch(line); match != nil {
+				fence, language, lines = match[1], match[2], nil
+			}
+			continue
+		}
+		if closesFence(line, fence) {
+			blocks = append(blocks, CodeBlock{language, strings.Join(lines, "\n") + "\n"})
+			fence = ""
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if fence != "" && len(lines) > 0 {
+		blocks = append(blocks, CodeBlock{language, strings.Join(lines, "\n") + "\n"})
+	}
+	return blocks
+}
+
+// closesFence reports if a line ends the code block opened by fence, which takes at least as many of
+// the same marks with nothing after them but spaces, so a line like ```go inside the block is code
+func closesFence(line, fence string) bool {
+	marks := strings.TrimSpace(line)
+	return len(marks) >= len(fence) && strings.Trim(marks, fence[:1]) == ""
+}
+
+// splitRow returns the cells of a table row like "| a | b |"
+func splitRow(text string) []string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "|")
+	text = strings.TrimSuffix(text, "|")
+	cells := strings.Split(text, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// isSeparator reports if a row is the line of dashes under a table header
+func isSeparator(row []string) bool {
+	for _, cell := range row {
+		if !cellPattern.MatchString(cell) {
+			return false
+		}
+	}
+	return true
+}
+
+// safeCut returns how much of a partial line can be written without splitting emphasis, code or
+// a link, ending after the last space
+func safeCut(text string) int {
+	for cut := strings.LastIndexByte(text, ' ') + 1; cut > 0; cut = strings.LastIndexByte(text[:cut-1], ' ') + 1 {
+		prefix := text[:cut]
+		if strings.Count(prefix, "`")%2 == 0 &&
+			strings.Count(prefix, "**")%2 == 0 &&
+			strings.Count(prefix, "~~")%2 == 0 &&
+			strings.Count(strings.ReplaceAll(prefix, "**", ""), "*")%2 == 0 &&
+			strings.LastIndexByte(prefix, '[') <= strings.LastIndexByte(prefix, ')') {
+			return cut
+		}
+	}
+	return 0
+}
+
+// RenderInline styles the code, emphasis and links in one line of Markdown
+func RenderInline(text string) string {
+	var sb strings.Builder
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		switch {
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				sb.WriteString(WrapText(Codes{ESC_CYAN}, rest[1:end+1]))
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], rest[:2]); end > 0 {
+				sb.WriteString(WrapText(Codes{ESC_BOLD}, RenderInline(rest[2:end+2])))
+				i += end + 4
+				continue
+			}
+		case strings.HasPrefix(rest, "~~"):
+			if end := strings.Index(rest[2:], "~~"); end > 0 {
+				sb.WriteString(WrapText(Codes{ESC_STRIKETHROUGH}, RenderInline(rest[2:end+2])))
+				i += end + 4
+				continue
+			}
+		case rest[0] == '*' || (rest[0] == '_' && (i == 0 || !isWordByte(text[i-1]))):
+			if end := closingEmphasis(rest); end > 0 {
+				sb.WriteString(WrapText(Codes{ESC_ITALIC}, RenderInline(rest[1:end])))
+				i += end + 1
+				continue
+			}
+		case rest[0] == '[':
+			if middle := strings.Index(rest, "]("); middle > 0 {
+				if end := strings.IndexByte(rest[middle:], ')'); end > 0 {
+					sb.WriteString(WrapText(Codes{ESC_UNDERLINE}, rest[1:middle]))
+					sb.WriteString(WrapText(Codes{ESC_FAINT}, " ("+rest[middle+2:middle+end]+")"))
+					i += middle + end + 1
+					continue
+				}
+			}
+		}
+		sb.WriteByte(text[i])
+		i++
+	}
+	return sb.String()
+}
+
+// closingEmphasis finds the mark closing the single * or _ at the start of text, which must not be
+// followed by a word character for _ so snake_case names are left alone
+func closingEmphasis(text string) int {
+	mark := text[0]
+	if len(text) < 3 || text[1] == ' ' || text[1] == mark {
+		return -1
+	}
+	for i := 2; i < len(text); i++ {
+		if text[i] != mark || text[i-1] == ' ' {
+			continue
+		}
+		if mark == '_' && i+1 < len(text) && isWordByte(text[i+1]) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
+
+// styled wraps text in the codes, leaving it alone when there are none
+func styled(codes Codes, text string) string {
+	if len(codes) == 0 {
+		return text
+	}
+	return WrapText(codes, text)
+}
+
+// VisibleWidth returns how many columns text takes on the terminal, ignoring color codes
+func VisibleWidth(text string) int {
+	return utf8.RuneCountInString(escapePattern.ReplaceAllString(text, ""))
+}
@@ -0,0 +1,104 @@
+// **********************************************************************************************100
+/*
+Tests for the Markdown renderer and code block finder: fences which open and close code blocks,
+headings, lists, quotes and tables, and text which arrives a piece at a time.
+
+created by Thomas.Cherry.gmail.com
+*/
+
+package lib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// render runs the markdown through a renderer 10 columns wide, written in pieces of size bytes or
+// all at once when size is zero, with colors off so only the layout is compared
+func render(t *testing.T, markdown string, size int) string {
+	t.Helper()
+	saved := ColorEnabled
+	ColorEnabled = false
+	defer func() { ColorEnabled = saved }()
+
+	var out strings.Builder
+	renderer := NewMarkdownRenderer(&out, 10)
+	if size == 0 {
+		size = len(markdown) + 1
+	}
+	for start := 0; start < len(markdown); start += size {
+		renderer.Write([]byte(markdown[start:min(start+size, len(markdown))]))
+	}
+	renderer.Flush()
+	return out.String()
+}
+
+func TestMarkdownRenderer(t *testing.T) {
+	top, bottom := "┌─────────\n", "└─────────\n"
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{"paragraph", "Some *plain* text\n", "Some plain text\n"},
+		{"heading", "# Title\n", "Title\n"},
+		{"bullets", "- one\n* two\n", "• one\n• two\n"},
+		{"ordered", "1. one\n2) two\n", "1. one\n2) two\n"},
+		{"quote", "> said\n", "│ said\n"},
+		{"rule", "---\n", "──────────\n"},
+		{"link", "see [docs](http://x)\n", "see docs (http://x)\n"},
+		{"table", "| a | bb |\n|---|---|\n| ccc | d |\n", "a   │ bb\n────┼───\nccc │ d \n"},
+
+		// code blocks
+		{"code block", "```\nx := 1\n```\n", top + "│ x := 1\n" + bottom},
+		{"code block with a language", "```go\nx := 1\n```\n", "┌─ go ────\n│ x := 1\n" + bottom},
+		{"tilde fence", "~~~\ncode\n~~~\n", top + "│ code\n" + bottom},
+		{"fence with a language inside", "````\n```go\n````\n", top + "│ ```go\n" + bottom},
+		{"fence with text inside", "```\n``` not a fence\n```\n", top + "│ ``` not a fence\n" + bottom},
+		{"shorter fence inside", "````\n```\n````\n", top + "│ ```\n" + bottom},
+		{"other fence inside", "```\n~~~\n```\n", top + "│ ~~~\n" + bottom},
+		{"longer closing fence", "```\ncode\n`````\n", top + "│ code\n" + bottom},
+		{"closing fence with spaces", "```\ncode\n  ```  \n", top + "│ code\n" + bottom},
+		{"markdown inside code", "```\n# not a heading\n```\n", top + "│ # not a heading\n" + bottom},
+		{"tabs in code", "```\n\tx\n```\n", top + "│     x\n" + bottom},
+		{"unclosed code block", "```\ncode", top + "│ code\n" + bottom},
+		{"text after code", "```\ncode\n```\nafter\n", top + "│ code\n" + bottom + "after\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := render(t, test.markdown, 0); got != test.want {
+				t.Errorf("render(%q) = %q, want %q", test.markdown, got, test.want)
+			}
+			// streamed a few bytes at a time the result must be the same
+			if got := render(t, test.markdown, 3); got != test.want {
+				t.Errorf("render(%q) in pieces = %q, want %q", test.markdown, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []CodeBlock
+	}{
+		{"no blocks", "just text", nil},
+		{"one block", "text\n```go\nx := 1\n```\nmore", []CodeBlock{{"go", "x := 1\n"}}},
+		{"two blocks", "```\na\n```\n~~~sh\nb\n~~~", []CodeBlock{{"", "a\n"}, {"sh", "b\n"}}},
+		{"fence with a language inside", "````md\n```go\nx\n```\n````", []CodeBlock{{"md", "```go\nx\n```\n"}}},
+		{"fence with text inside", "```\n```x\n```", []CodeBlock{{"", "```x\n"}}},
+		{"other fence inside", "~~~\n```\n~~~", []CodeBlock{{"", "```\n"}}},
+		{"closing fence with spaces", "```\na\n```   ", []CodeBlock{{"", "a\n"}}},
+		{"unclosed block", "```py\nprint()", []CodeBlock{{"py", "print()\n"}}},
+		{"empty unclosed block", "```py", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := CodeBlocks(test.markdown); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("CodeBlocks(%q) = %#v, want %#v", test.markdown, got, test.want)
+			}
+		})
+	}
+}
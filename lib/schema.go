@@ -0,0 +1,347 @@
+// **********************************************************************************************100
+/*
+Checks a decoded JSON value against a JSON Schema. Covers the parts of the standard used to describe
+data, like types, properties, items, enums, ranges, patterns, combinations and local $ref links.
+
+created by Thomas.Cherry.gmail.com
+*/
+
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaError is one way a value does not match the schema, Path is like $.items[2].name
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+type schemaValidator struct {
+	root   map[string]any
+	errors []SchemaError
+}
+
+// ValidateSchema checks value, as decoded by encoding/json, against schema and returns every
+// problem found. An empty result means the value is valid.
+func ValidateSchema(schema map[string]any, value any) []SchemaError {
+	validator := &schemaValidator{root: schema}
+	validator.check(schema, value, "$", 0)
+	return validator.errors
+}
+
+func (v *schemaValidator) fail(path, format string, args ...any) {
+	v.errors = append(v.errors, SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// resolve follows a local reference like #/$defs/Address
+func (v *schemaValidator) resolve(ref string) (map[string]any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("only local references are supported, not %s", ref)
+	}
+	var node any = v.root
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimPrefix(ref, "#"), "/"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		object, okay := node.(map[string]any)
+		if !okay {
+			return nil, fmt.Errorf("reference %s not found", ref)
+		}
+		if node, okay = object[part]; !okay {
+			return nil, fmt.Errorf("reference %s not found", ref)
+		}
+	}
+	schema, okay := node.(map[string]any)
+	if !okay {
+		return nil, fmt.Errorf("reference %s is not a schema", ref)
+	}
+	return schema, nil
+}
+
+// matches runs a sub schema without recording errors, used by anyOf, oneOf and not
+func (v *schemaValidator) matches(schema any, value any, depth int) bool {
+	sub := &schemaValidator{root: v.root}
+	sub.check(schema, value, "$", depth)
+	return len(sub.errors) == 0
+}
+
+func (v *schemaValidator) check(rawSchema any, value any, path string, depth int) {
+	if depth > 64 {
+		v.fail(path, "schema nests too deeply, is there a reference loop?")
+		return
+	}
+	switch typed := rawSchema.(type) {
+	case bool:
+		if !typed {
+			v.fail(path, "no value is allowed here")
+		}
+		return
+	case map[string]any:
+	default:
+		return
+	}
+	schema := rawSchema.(map[string]any)
+
+	if ref, okay := schema["$ref"].(string); okay {
+		target, err := v.resolve(ref)
+		if err != nil {
+			v.fail(path, "%v", err)
+			return
+		}
+		v.check(target, value, path, depth+1)
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		actual := jsonType(value)
+		if !slices.Contains(types, actual) && !(actual == "integer" && slices.Contains(types, "number")) {
+			v.fail(path, "expected %s but found %s", strings.Join(types, " or "), describeJson(value))
+			return
+		}
+	}
+	if options, okay := schema["enum"].([]any); okay && !containsJson(options, value) {
+		v.fail(path, "%s is not one of %s", describeJson(value), compactJson(options))
+	}
+	if constant, okay := schema["const"]; okay && !equalJson(constant, value) {
+		v.fail(path, "expected %s but found %s", compactJson(constant), describeJson(value))
+	}
+
+	switch typed := value.(type) {
+	case map[string]any:
+		v.checkObject(schema, typed, path, depth)
+	case []any:
+		v.checkArray(schema, typed, path, depth)
+	case string:
+		v.checkString(schema, typed, path)
+	case float64:
+		v.checkNumber(schema, typed, path)
+	}
+
+	if all, okay := schema["allOf"].([]any); okay {
+		for _, sub := range all {
+			v.check(sub, value, path, depth+1)
+		}
+	}
+	if choices, okay := schema["anyOf"].([]any); okay {
+		found := false
+		for _, sub := range choices {
+			if v.matches(sub, value, depth+1) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "does not match any of the %d allowed schemas", len(choices))
+		}
+	}
+	if one, okay := schema["oneOf"].([]any); okay {
+		count := 0
+		for _, sub := range one {
+			if v.matches(sub, value, depth+1) {
+				count++
+			}
+		}
+		if count != 1 {
+			v.fail(path, "must match exactly one of %d schemas but matches %d", len(one), count)
+		}
+	}
+	if not, okay := schema["not"]; okay && v.matches(not, value, depth+1) {
+		v.fail(path, "matches a schema it must not match")
+	}
+}
+
+func (v *schemaValidator) checkObject(schema map[string]any, object map[string]any, path string, depth int) {
+	properties, _ := schema["properties"].(map[string]any)
+	if required, okay := schema["required"].([]any); okay {
+		for _, name := range required {
+			if key, okay := name.(string); okay {
+				if _, found := object[key]; !found {
+					v.fail(path, "missing required property %s", key)
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		childPath := path + "." + key
+		if sub, found := properties[key]; found {
+			v.check(sub, object[key], childPath, depth+1)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(childPath, "property is not allowed")
+			}
+		case map[string]any:
+			v.check(additional, object[key], childPath, depth+1)
+		}
+	}
+
+	if minimum, okay := schemaNumber(schema, "minProperties"); okay && float64(len(object)) < minimum {
+		v.fail(path, "needs at least %v properties but has %d", minimum, len(object))
+	}
+	if maximum, okay := schemaNumber(schema, "maxProperties"); okay && float64(len(object)) > maximum {
+		v.fail(path, "allows at most %v properties but has %d", maximum, len(object))
+	}
+}
+
+func (v *schemaValidator) checkArray(schema map[string]any, array []any, path string, depth int) {
+	prefix, _ := schema["prefixItems"].([]any)
+	for i, item := range array {
+		childPath := fmt.Sprintf("%s[%d]", path, i)
+		if i < len(prefix) {
+			v.check(prefix[i], item, childPath, depth+1)
+		} else if items, found := schema["items"]; found {
+			v.check(items, item, childPath, depth+1)
+		}
+	}
+	if minimum, okay := schemaNumber(schema, "minItems"); okay && float64(len(array)) < minimum {
+		v.fail(path, "needs at least %v items but has %d", minimum, len(array))
+	}
+	if maximum, okay := schemaNumber(schema, "maxItems"); okay && float64(len(array)) > maximum {
+		v.fail(path, "allows at most %v items but has %d", maximum, len(array))
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range array {
+			for j := i + 1; j < len(array); j++ {
+				if equalJson(array[i], array[j]) {
+					v.fail(fmt.Sprintf("%s[%d]", path, j), "repeats item %d, items must be unique", i)
+				}
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) checkString(schema map[string]any, text string, path string) {
+	length := float64(utf8.RuneCountInString(text))
+	if minimum, okay := schemaNumber(schema, "minLength"); okay && length < minimum {
+		v.fail(path, "must be at least %v characters but is %v", minimum, length)
+	}
+	if maximum, okay := schemaNumber(schema, "maxLength"); okay && length > maximum {
+		v.fail(path, "must be at most %v characters but is %v", maximum, length)
+	}
+	if pattern, okay := schema["pattern"].(string); okay {
+		expression, err := regexp.Compile(pattern)
+		if err != nil {
+			v.fail(path, "schema pattern %s can not be used: %v", pattern, err)
+		} else if !expression.MatchString(text) {
+			v.fail(path, "%q does not match the pattern %s", text, pattern)
+		}
+	}
+}
+
+func (v *schemaValidator) checkNumber(schema map[string]any, number float64, path string) {
+	if minimum, okay := schemaNumber(schema, "minimum"); okay && number < minimum {
+		v.fail(path, "%v is less than the minimum of %v", number, minimum)
+	}
+	if maximum, okay := schemaNumber(schema, "maximum"); okay && number > maximum {
+		v.fail(path, "%v is more than the maximum of %v", number, maximum)
+	}
+	if minimum, okay := schemaNumber(schema, "exclusiveMinimum"); okay && number <= minimum {
+		v.fail(path, "%v must be more than %v", number, minimum)
+	}
+	if maximum, okay := schemaNumber(schema, "exclusiveMaximum"); okay && number >= maximum {
+		v.fail(path, "%v must be less than %v", number, maximum)
+	}
+	if multiple, okay := schemaNumber(schema, "multipleOf"); okay && multiple > 0 {
+		if quotient := number / multiple; math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			v.fail(path, "%v is not a multiple of %v", number, multiple)
+		}
+	}
+}
+
+/**************************************/
+// MARK: - Helpers
+
+func schemaTypes(value any) []string {
+	switch typed := value.(type) {
+	case string:
+		return []string{typed}
+	case []any:
+		types := []string{}
+		for _, item := range typed {
+			if name, okay := item.(string); okay {
+				types = append(types, name)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func schemaNumber(schema map[string]any, key string) (float64, bool) {
+	number, okay := schema[key].(float64)
+	return number, okay
+}
+
+// jsonType names the JSON type of a decoded value, whole numbers are integers
+func jsonType(value any) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if typed == math.Trunc(typed) && !math.IsInf(typed, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func describeJson(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	}
+	return jsonType(value) + " " + compactJson(value)
+}
+
+func compactJson(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func equalJson(a, b any) bool {
+	return compactJson(a) == compactJson(b)
+}
+
+func containsJson(list []any, value any) bool {
+	for _, item := range list {
+		if equalJson(item, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+// **********************************************************************************************100
+/*
+Tests for the JSON Schema checks: types, required properties, enums, nested objects and arrays, and
+the errors reported for documents which do not match.
+
+created by Thomas.Cherry.gmail.com
+*/
+
+package lib
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// decode reads a JSON document for a test, failing the test if it is not valid JSON
+func decode(t *testing.T, text string) any {
+	t.Helper()
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		t.Fatalf("bad test JSON %s: %v", text, err)
+	}
+	return value
+}
+
+// schemaMessages runs the schema against the document and returns each error as text
+func schemaMessages(t *testing.T, schema, document string) []string {
+	t.Helper()
+	var messages []string
+	for _, err := range ValidateSchema(decode(t, schema).(map[string]any), decode(t, document)) {
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
+
+const personSchema = `{
+	"type": "object",
+	"required": ["name", "age"],
+	"properties": {
+		"name": {"type": "string", "minLength": 1},
+		"age": {"type": "integer", "minimum": 0},
+		"role": {"enum": ["admin", "user"]},
+		"address": {
+			"type": "object",
+			"required": ["city"],
+			"properties": {"city": {"type": "string"}},
+			"additionalProperties": false
+		},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2, "uniqueItems": true}
+	}
+}`
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		document string
+	}{
+		{"whole person", personSchema, `{"name": "Ann", "age": 30, "role": "admin",
+			"address": {"city": "Oslo"}, "tags": ["a", "b"]}`},
+		{"only required", personSchema, `{"name": "Ann", "age": 0}`},
+		{"extra top level property", personSchema, `{"name": "Ann", "age": 1, "other": true}`},
+
+		// types
+		{"string", `{"type": "string"}`, `"text"`},
+		{"integer is a number", `{"type": "number"}`, `3`},
+		{"fraction is a number", `{"type": "number"}`, `3.5`},
+		{"whole float is an integer", `{"type": "integer"}`, `3.0`},
+		{"null", `{"type": "null"}`, `null`},
+		{"list of types", `{"type": ["string", "null"]}`, `null`},
+		{"no type", `{}`, `[1, "a", {}]`},
+
+		// enums and constants
+		{"enum string", `{"enum": ["a", "b"]}`, `"b"`},
+		{"enum mixed", `{"enum": [1, null, {"x": 1}]}`, `{"x": 1}`},
+		{"const", `{"const": [1, 2]}`, `[1, 2]`},
+
+		// arrays
+		{"items", `{"type": "array", "items": {"type": "integer"}}`, `[1, 2, 3]`},
+		{"empty array", `{"type": "array", "items": {"type": "integer"}, "minItems": 0}`, `[]`},
+		{"prefix items", `{"prefixItems": [{"type": "string"}, {"type": "integer"}]}`, `["a", 1, true]`},
+		{"array of objects", `{"type": "array", "items": {"type": "object", "required": ["id"]}}`,
+			`[{"id": 1}, {"id": 2}]`},
+
+		// combinations and references
+		{"any of", `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `4`},
+		{"one of", `{"oneOf": [{"minimum": 5}, {"maximum": 2}]}`, `7`},
+		{"not", `{"not": {"type": "string"}}`, `1`},
+		{"local reference", `{"$defs": {"id": {"type": "integer"}}, "properties": {"id": {"$ref": "#/$defs/id"}}}`,
+			`{"id": 4}`},
+		{"true schema", `{"properties": {"any": true}}`, `{"any": [1]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := schemaMessages(t, test.schema, test.document); len(got) > 0 {
+				t.Errorf("ValidateSchema(%s) = %q, want no errors", test.document, got)
+			}
+		})
+	}
+}
+
+func TestValidateSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		document string
+		want     []string
+	}{
+		{"missing required", personSchema, `{"name": "Ann"}`, []string{"$: missing required property age"}},
+		{"missing all required", personSchema, `{}`,
+			[]string{"$: missing required property name", "$: missing required property age"}},
+		{"wrong top level type", personSchema, `[]`, []string{"$: expected object but found an array"}},
+		{"wrong property type", personSchema, `{"name": 5, "age": 1}`,
+			[]string{"$.name: expected string but found integer 5"}},
+		{"fraction is not an integer", personSchema, `{"name": "Ann", "age": 1.5}`,
+			[]string{"$.age: expected integer but found number 1.5"}},
+		{"below the minimum", personSchema, `{"name": "Ann", "age": -1}`,
+			[]string{"$.age: -1 is less than the minimum of 0"}},
+		{"too short", personSchema, `{"name": "", "age": 1}`,
+			[]string{"$.name: must be at least 1 characters but is 0"}},
+		{"not in the enum", personSchema, `{"name": "Ann", "age": 1, "role": "root"}`,
+			[]string{`$.role: string "root" is not one of ["admin","user"]`}},
+
+		// nested objects and arrays
+		{"nested missing required", personSchema, `{"name": "Ann", "age": 1, "address": {}}`,
+			[]string{"$.address: missing required property city"}},
+		{"nested property not allowed", personSchema, `{"name": "Ann", "age": 1, "address": {"city": "Oslo", "zip": 1}}`,
+			[]string{"$.address.zip: property is not allowed"}},
+		{"wrong item type", personSchema, `{"name": "Ann", "age": 1, "tags": ["a", 2]}`,
+			[]string{"$.tags[1]: expected string but found integer 2"}},
+		{"too many items", personSchema, `{"name": "Ann", "age": 1, "tags": ["a", "b", "c"]}`,
+			[]string{"$.tags: allows at most 2 items but has 3"}},
+		{"repeated items", personSchema, `{"name": "Ann", "age": 1, "tags": ["a", "a"]}`,
+			[]string{"$.tags[1]: repeats item 0, items must be unique"}},
+		{"errors in several items", `{"items": {"type": "object", "required": ["id"]}}`, `[{}, {"id": 1}, {}]`,
+			[]string{"$[0]: missing required property id", "$[2]: missing required property id"}},
+
+		// other checks
+		{"null is not a string", `{"type": "string"}`, `null`, []string{"$: expected string but found null null"}},
+		{"list of types", `{"type": ["string", "null"]}`, `true`,
+			[]string{"$: expected string or null but found boolean true"}},
+		{"const", `{"const": "x"}`, `"y"`, []string{`$: expected "x" but found string "y"`}},
+		{"pattern", `{"pattern": "^a+$"}`, `"ab"`, []string{`$: "ab" does not match the pattern ^a+$`}},
+		{"bad pattern", `{"pattern": "("}`, `"a"`,
+			[]string{"$: schema pattern ( can not be used: error parsing regexp: missing closing ): `(`"}},
+		{"none of any of", `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `true`,
+			[]string{"$: does not match any of the 2 allowed schemas"}},
+		{"more than one of", `{"oneOf": [{"minimum": 1}, {"maximum": 5}]}`, `3`,
+			[]string{"$: must match exactly one of 2 schemas but matches 2"}},
+		{"not", `{"not": {"type": "integer"}}`, `1`, []string{"$: matches a schema it must not match"}},
+		{"false schema", `{"properties": {"never": false}}`, `{"never": 1}`,
+			[]string{"$.never: no value is allowed here"}},
+		{"missing reference", `{"$ref": "#/$defs/none"}`, `1`, []string{"$: reference #/$defs/none not found"}},
+		{"remote reference", `{"$ref": "http://example.com/schema"}`, `1`,
+			[]string{"$: only local references are supported, not http://example.com/schema"}},
+		{"reference loop", `{"$defs": {"a": {"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}`, `1`,
+			[]string{"$: schema nests too deeply, is there a reference loop?"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := schemaMessages(t, test.schema, test.document)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ValidateSchema(%s) = %q, want %q", test.document, got, test.want)
+			}
+		})
+	}
+}
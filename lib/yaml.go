@@ -0,0 +1,219 @@
+// **********************************************************************************************100
+/*
+A small YAML writer which turns anything encoding/json can marshal into block style YAML, keeping
+the field order of structs.
+
+created by Thomas.Cherry.gmail.com
+*/
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// yamlPair is one key and value of an object, kept in a slice so the order is not lost
+type yamlPair struct {
+	key   string
+	value any
+}
+
+type yamlObject []yamlPair
+
+// YamlFromStruct marshals data as YAML using the json tags and field order of the data
+func YamlFromStruct[K any](data K) ([]byte, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.UseNumber()
+	tree, err := readYamlNode(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	switch tree.(type) {
+	case yamlObject, []any:
+		writeYamlNode(&sb, tree, 0)
+	default:
+		sb.WriteString(yamlScalar(tree, 0) + "\n")
+	}
+	return []byte(sb.String()), nil
+}
+
+// readYamlNode reads one value from the decoder, objects become yamlObject and arrays []any
+func readYamlNode(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, isDelim := token.(json.Delim)
+	if !isDelim {
+		return token, nil
+	}
+
+	switch delim {
+	case '{':
+		object := yamlObject{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readYamlNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, yamlPair{key: fmt.Sprint(key), value: value})
+		}
+		_, err = decoder.Token()
+		return object, err
+	case '[':
+		array := []any{}
+		for decoder.More() {
+			value, err := readYamlNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = decoder.Token()
+		return array, err
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+// writeYamlNode writes an object or array, each line starting with indent spaces
+func writeYamlNode(sb *strings.Builder, node any, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch typed := node.(type) {
+	case yamlObject:
+		for _, pair := range typed {
+			sb.WriteString(pad + yamlKey(pair.key) + ":")
+			writeYamlChild(sb, pair.value, indent+2)
+		}
+	case []any:
+		for _, item := range typed {
+			sb.WriteString(pad + "-")
+			if object, okay := item.(yamlObject); okay && len(object) > 0 {
+				// the first key goes on the same line as the dash
+				var child strings.Builder
+				writeYamlNode(&child, object, indent+2)
+				sb.WriteString(" " + strings.TrimLeft(child.String(), " "))
+				continue
+			}
+			writeYamlChild(sb, item, indent+2)
+		}
+	}
+}
+
+// writeYamlChild writes the value after a key or dash, nested blocks start on the next line
+func writeYamlChild(sb *strings.Builder, value any, indent int) {
+	switch typed := value.(type) {
+	case yamlObject:
+		if len(typed) == 0 {
+			sb.WriteString(" {}\n")
+			return
+		}
+		sb.WriteString("\n")
+		writeYamlNode(sb, typed, indent)
+	case []any:
+		if len(typed) == 0 {
+			sb.WriteString(" []\n")
+			return
+		}
+		sb.WriteString("\n")
+		writeYamlNode(sb, typed, indent)
+	default:
+		sb.WriteString(" " + yamlScalar(value, indent) + "\n")
+	}
+}
+
+func yamlKey(key string) string {
+	if yamlNeedsQuotes(key) {
+		return jsonQuote(key)
+	}
+	return key
+}
+
+// yamlScalar formats a number, bool, null or string. Strings with line breaks use a literal block.
+func yamlScalar(value any, indent int) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return fmt.Sprint(typed)
+	case json.Number:
+		return typed.String()
+	case string:
+		if yamlLiteral(typed) {
+			chomp := "-"
+			body := typed
+			if strings.HasSuffix(body, "\n") {
+				chomp = ""
+				body = strings.TrimSuffix(body, "\n")
+				if strings.HasSuffix(body, "\n") {
+					chomp = "+"
+				}
+			}
+			pad := strings.Repeat(" ", indent)
+			lines := strings.Split(body, "\n")
+			for i, line := range lines {
+				if line != "" {
+					lines[i] = pad + line
+				}
+			}
+			return "|" + chomp + "\n" + strings.Join(lines, "\n")
+		}
+		if yamlNeedsQuotes(typed) {
+			return jsonQuote(typed)
+		}
+		return typed
+	}
+	return jsonQuote(fmt.Sprint(value))
+}
+
+// yamlLiteral is true for strings with line breaks which read back the same from a literal block.
+// The indent of a block is found from its first line with text, so that line can not start with
+// white space, and a block of only empty lines would lose them.
+func yamlLiteral(text string) bool {
+	if !strings.Contains(text, "\n") || strings.Contains(text, "\r") {
+		return false
+	}
+	first := strings.TrimLeft(text, "\n")
+	return first != "" && first[0] != ' ' && first[0] != '\t'
+}
+
+// yamlNeedsQuotes is true for strings which would be read back as something else, or not at all
+func yamlNeedsQuotes(text string) bool {
+	if text == "" || strings.TrimSpace(text) != text {
+		return true
+	}
+	switch strings.ToLower(text) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return true
+	}
+	if strings.ContainsAny(text[:1], "-?:,[]{}#&*!|>'\"%@`0123456789.+") {
+		return true
+	}
+	if strings.Contains(text, ": ") || strings.Contains(text, " #") || strings.HasSuffix(text, ":") {
+		return true
+	}
+	for _, r := range text {
+		if r < ' ' || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonQuote(text string) string {
+	data, _ := json.Marshal(text)
+	return string(data)
+}
@@ -0,0 +1,78 @@
+// **********************************************************************************************100
+/*
+Tests for the YAML writer: field order, nesting, quoting of strings which would read back as
+something else, and literal blocks for text with line breaks.
+
+created by Thomas.Cherry.gmail.com
+*/
+
+package lib
+
+import (
+	"testing"
+)
+
+func TestYamlFromStruct(t *testing.T) {
+	type inner struct {
+		City string `json:"city"`
+		Zip  int    `json:"zip,omitempty"`
+	}
+	type record struct {
+		Name    string         `json:"name"`
+		Age     int            `json:"age"`
+		Address inner          `json:"address"`
+		Tags    []string       `json:"tags"`
+		Extra   map[string]any `json:"extra"`
+	}
+	tests := []struct {
+		name string
+		data any
+		want string
+	}{
+		{"struct keeps the field order",
+			record{Name: "Ann", Age: 30, Address: inner{City: "Oslo"}, Tags: []string{"a", "b"}, Extra: map[string]any{}},
+			"name: Ann\nage: 30\naddress:\n  city: Oslo\ntags:\n  - a\n  - b\nextra: {}\n"},
+		{"empty list and null", record{Tags: []string{}}, "name: \"\"\nage: 0\naddress:\n  city: \"\"\ntags: []\nextra: null\n"},
+		{"list of objects", []inner{{"Oslo", 1}, {"Rome", 2}}, "- city: Oslo\n  zip: 1\n- city: Rome\n  zip: 2\n"},
+		{"list in a list", [][]int{{1, 2}, {}}, "-\n  - 1\n  - 2\n- []\n"},
+		{"top level scalar", 3.5, "3.5\n"},
+
+		// quoting
+		{"plain string", map[string]string{"v": "hello world"}, "v: hello world\n"},
+		{"empty string", map[string]string{"v": ""}, "v: \"\"\n"},
+		{"looks like a bool", map[string]string{"v": "yes"}, "v: \"yes\"\n"},
+		{"looks like a number", map[string]string{"v": "42"}, "v: \"42\"\n"},
+		{"looks like null", map[string]string{"v": "~"}, "v: \"~\"\n"},
+		{"leading space", map[string]string{"v": " a"}, "v: \" a\"\n"},
+		{"starts with a dash", map[string]string{"v": "- a"}, "v: \"- a\"\n"},
+		{"colon and space", map[string]string{"v": "a: b"}, "v: \"a: b\"\n"},
+		{"comment", map[string]string{"v": "a #b"}, "v: \"a #b\"\n"},
+		{"control character", map[string]string{"v": "a\tb"}, "v: \"a\\tb\"\n"},
+		{"quoted key", map[string]string{"a: b": "c"}, "\"a: b\": c\n"},
+
+		// line breaks
+		{"literal block", map[string]string{"v": "one\ntwo"}, "v: |-\n  one\n  two\n"},
+		{"literal block with a final break", map[string]string{"v": "one\ntwo\n"}, "v: |\n  one\n  two\n"},
+		{"literal block keeping breaks", map[string]string{"v": "one\n\n"}, "v: |+\n  one\n\n"},
+		{"literal block with an empty line", map[string]string{"v": "one\n\ntwo"}, "v: |-\n  one\n\n  two\n"},
+		{"later lines indented", map[string]string{"v": "one\n  two"}, "v: |-\n  one\n    two\n"},
+		{"leading break", map[string]string{"v": "\none"}, "v: |-\n\n  one\n"},
+		{"first line indented", map[string]string{"v": "  one\ntwo"}, "v: \"  one\\ntwo\"\n"},
+		{"first text line indented", map[string]string{"v": "\n  indented"}, "v: \"\\n  indented\"\n"},
+		{"first line starts with a tab", map[string]string{"v": "\tone\ntwo"}, "v: \"\\tone\\ntwo\"\n"},
+		{"only breaks", map[string]string{"v": "\n\n"}, "v: \"\\n\\n\"\n"},
+		{"carriage return", map[string]string{"v": "one\r\ntwo"}, "v: \"one\\r\\ntwo\"\n"},
+		{"nested literal block", map[string]inner{"a": {City: "one\ntwo"}}, "a:\n  city: |-\n    one\n    two\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := YamlFromStruct(test.data)
+			if err != nil {
+				t.Fatalf("YamlFromStruct(%#v) failed: %v", test.data, err)
+			}
+			if string(got) != test.want {
+				t.Errorf("YamlFromStruct(%#v) = %q, want %q", test.data, got, test.want)
+			}
+		})
+	}
+}
@@ -6,15 +6,19 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/peterh/liner"
 
 	"github.com/jceaser/ollama-query/app"
+	"github.com/jceaser/ollama-query/client"
 	"github.com/jceaser/ollama-query/lib"
 )
 
@@ -23,7 +27,8 @@ import (
 const (
 	ollamaServerURL2     = "http://ai.local:11434"
 	ollamaServerURL1     = "http://localhost:11434"
-	actionableItemFormat = "%10s %-15s %-23s %s"
+	actionableItemFormat = "%12s %-18s %-28s %s"
+	multiLineMark        = `"""` // starts and ends input which spans several lines
 )
 
 // ***************************************************************************80
@@ -66,13 +71,37 @@ func (a ActionableItems) String() string {
 	return sb.String()
 }
 
-func (a ActionableItem) Matches(command string) bool {
-	for _, trigger := range a.Triggers {
-		if strings.HasPrefix(trigger, command) {
-			return true
+// Find returns the action for a command, which is either a whole trigger or the start of the
+// triggers of only one action
+func (a ActionableItems) Find(command string) (ActionableItem, error) {
+	if command == "" {
+		return ActionableItem{}, fmt.Errorf("%w []", errInvalidOption)
+	}
+	var found []ActionableItem
+	var candidates []string
+	for _, item := range a {
+		matched := false
+		for _, trigger := range item.Triggers {
+			if trigger == command {
+				return item, nil
+			}
+			if strings.HasPrefix(trigger, command) {
+				candidates = append(candidates, trigger)
+				matched = true
+			}
+		}
+		if matched {
+			found = append(found, item)
 		}
 	}
-	return false
+	switch len(found) {
+	case 0:
+		return ActionableItem{}, fmt.Errorf("%w [%s]", errInvalidOption, command)
+	case 1:
+		return found[0], nil
+	}
+	return ActionableItem{}, fmt.Errorf("%w [%s], it could be: %s", errAmbiguous, command,
+		strings.Join(candidates, ", "))
 }
 
 // print out an ActionableItem as a formatted string
@@ -82,13 +111,33 @@ func (a ActionableItem) String() string {
 }
 
 var actions = ActionableItems{
-	{"Chat", []string{"chat"}, app.Chat, "<model> <role> <prompt>", "Chat with model"},
+	{"Attach", []string{"attach"}, app.Attach, "[clear|<image>...]", "Attach images to the next prompt"},
+	{"Bench", []string{"bench"}, app.Bench, "[-n runs] [-unload] [-export file] <prompt-file> <model...>", "Compare the speed of models"},
+	{"Blocks", []string{"blocks"}, app.Blocks, "[list|save|pipe|copy] [n] [path|command...]", "Save code from the last answer"},
+	{"Chat", []string{"chat"}, app.Chat, "[-format f] [model] <role> <prompt...>", "Chat with model"},
+	{"Conversation", []string{"conversation", "conv"}, app.ConversationCommand, "[new|show|undo|model] [name]", "Manage the chat history"},
+	{"Copy", []string{"cp"}, app.CopyModel, "<source> <destination>", "Copy a model to a new name"},
+	{"Create", []string{"create"}, app.CreateModel, "<name> <Modelfile path>", "Create a model from a Modelfile"},
+	{"Delete", []string{"rm"}, app.DeleteModel, "[-force] <model>", "Delete a model"},
+	{"Edit", []string{"edit"}, app.Edit, "[model]", "Write a chat message in $EDITOR and send it"},
+	{"Embed", []string{"embed"}, app.Embed, "[similarity] [-model name] [model] [text...]", "Get embedding vectors"},
 	{"Exit", []string{"exit", "quit"}, Exit, "", "Exit the application"},
-	{"Generate", []string{"generate"}, app.GenerateText, "<name> <prompt>", "Converse using context"},
-	{"Help", []string{"help", "menu"}, Exit, "", "Display this menu"},
+	{"Format", []string{"format"}, app.SetFormat, "[json|yaml|table]", "Set the output format"},
+	{"Generate", []string{"generate"}, app.GenerateText, "[-format f] [-model name] [model] <prompt...>", "Converse using context"},
+	{"Help", []string{"help", "menu"}, Exit, "[command]", "Display this menu"},
 	{"List", []string{"ls", "list", "tags"}, app.ListModels, "", "List Models"},
+	{"Options", []string{"options"}, app.ShowOptions, "", "List model options"},
+	{"Profile", []string{"profile"}, app.SwitchProfile, "[name]", "List or switch profiles"},
 	{"Processes", []string{"ps", "processes"}, app.ExecutePS, "", "Execute ps command"},
-	{"Show", []string{"show", "details"}, app.ShowModelDetails, "<name>", "Show Model Details"},
+	{"Pull", []string{"pull"}, app.PullModel, "[-insecure] <model>", "Download a model"},
+	{"Push", []string{"push"}, app.PushModel, "[-insecure] <model>", "Upload a model to a registry"},
+	{"Render", []string{"render"}, app.SetRender, "[raw|markdown]", "Print answers raw or as Markdown"},
+	{"Session", []string{"session"}, app.SessionCommand, "save|load|list|delete [name]", "Save or restore a session"},
+	{"Set", []string{"set"}, app.SetOption, "<option> <value...>", "Set a model option"},
+	{"Show", []string{"show", "details"}, app.ShowModelDetails, "[model]", "Show Model Details"},
+	{"Stats", []string{"stats"}, app.Stats, "[on|off|reset]", "Token and timing statistics"},
+	{"Tools", []string{"tools"}, app.ToolsCommand, "[list|add|load|remove|clear] [name]", "Tools the model can call"},
+	{"Unset", []string{"unset"}, app.UnsetOption, "<option>|all", "Remove a model option"},
 	{"Version", []string{"version"}, app.GetVersion, "", "Get Version"},
 }
 
@@ -107,7 +156,17 @@ func Exit(context app.AppContext, args ...string) (map[string]string, error) {
 	os.Exit(0)
 	return nil, nil
 }
+
+// DisplayMenu shows the menu, or the detailed help for the command given
 func DisplayMenu(context app.AppContext, args ...string) (map[string]string, error) {
+	if len(args) > 0 {
+		item, err := actions.Find(args[0])
+		if err != nil {
+			return nil, err
+		}
+		writeActionHelp(context.Output, item)
+		return nil, nil
+	}
 	displayMenu()
 	return nil, nil
 }
@@ -117,7 +176,7 @@ func displayMenu() {
 	fmt.Println()
 	fmt.Println(strings.Repeat("*", 80))
 	fmt.Println(actions)
-	fmt.Println("Choose an option:")
+	fmt.Println("Choose an option, or type help <command> for details:")
 }
 
 func DrawLine() {
@@ -134,11 +193,58 @@ func askForChoice() string {
 	return strings.TrimSpace(choice)
 }
 
-func askForCommand(line *liner.State) string {
-	if name, err := line.Prompt(">"); err == nil {
-		return strings.TrimSpace(name)
+// askForCommand reads a command which may go on over several lines. A line ending in "\" is
+// continued on the next line, and text between """ marks may span lines. Everything after the first
+// line, or between the marks, is returned as the body to be sent as one argument.
+func askForCommand(line *liner.State) (command string, body string) {
+	first, err := line.Prompt(">")
+	if err != nil {
+		return "", ""
 	}
-	return ""
+
+	var lines []string
+	if command, rest, found := strings.Cut(first, multiLineMark); found {
+		if text, _, closed := strings.Cut(rest, multiLineMark); closed {
+			return strings.TrimSpace(command), text
+		}
+		lines = append(lines, rest)
+		for {
+			next, err := line.Prompt("...")
+			if err != nil {
+				return "", ""
+			}
+			if text, _, closed := strings.Cut(next, multiLineMark); closed {
+				lines = append(lines, text)
+				break
+			}
+			lines = append(lines, next)
+		}
+		return strings.TrimSpace(command), strings.Trim(strings.Join(lines, "\n"), "\n")
+	}
+
+	command, continued := strings.CutSuffix(first, "\\")
+	for continued {
+		next, err := line.Prompt("...")
+		if err != nil {
+			return "", ""
+		}
+		next, continued = strings.CutSuffix(next, "\\")
+		lines = append(lines, next)
+	}
+	return strings.TrimSpace(command), strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// historyEntry puts a command with a body back on one line, in a form which can be run again. A body
+// of several lines can not be put on one line without changing it, so it is left out of the history
+// and an empty entry is returned.
+func historyEntry(command, body string) string {
+	if body == "" {
+		return command
+	}
+	if strings.Contains(body, "\n") {
+		return ""
+	}
+	return command + " " + multiLineMark + body + multiLineMark
 }
 
 func jsonToIntArray(jsonStr string) ([]int, error) {
@@ -150,24 +256,225 @@ func jsonToIntArray(jsonStr string) ([]int, error) {
 	return intArray, err
 }
 
+// applyMetadata updates the AppContext with values an action has returned, like a new "context"
+func applyMetadata(context *app.AppContext, metadata map[string]string) {
+	if metadata == nil {
+		return
+	}
+	if sessionContext, okay := metadata["context"]; okay {
+		intArray, err := jsonToIntArray(sessionContext)
+		if err == nil {
+			context.Context = intArray
+		}
+	}
+	if host, okay := metadata["host"]; okay {
+		context.HostName = client.ParseHost(host)
+		context.Client = client.New(context.HostName)
+	}
+	if system, okay := metadata["system"]; okay {
+		context.System = system
+	}
+	if format, okay := metadata["format"]; okay {
+		context.Format = format
+	}
+	if model, okay := metadata["model"]; okay {
+		context.Model = model
+	}
+	if render, okay := metadata["render"]; okay {
+		context.Render = render
+	}
+	if profile, okay := metadata["profile"]; okay {
+		context.Profile = profile
+	}
+}
+
+// setupHost picks the server from, in order, the -host flag, OLLAMA_HOST, the profile, or the
+// default. The profile is applied even when its host is not used.
+func setupHost(context *app.AppContext, hostFlag, profileName string) {
+	config, err := app.LoadConfig()
+	if err != nil {
+		lib.Log.Warn.Printf("%v\n", err)
+	}
+	if profileName == "" {
+		profileName = config.DefaultProfile
+	}
+	if profileName != "" {
+		profile, err := config.Profile(profileName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		metadata, err := app.ApplyProfile(*context, profileName, profile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		applyMetadata(context, metadata)
+	}
+
+	if host := os.Getenv("OLLAMA_HOST"); host != "" {
+		context.HostName = client.ParseHost(host)
+	}
+	if hostFlag != "" {
+		context.HostName = client.ParseHost(hostFlag)
+	}
+	if context.HostName == "" {
+		context.HostName = ollamaServerURL2
+	}
+	context.Client = client.New(context.HostName)
+}
+
 // ***********************************40
 
-func setup_liner(line *liner.State) string {
+var (
+	errInvalidOption = errors.New("invalid option")
+	errAmbiguous     = errors.New("ambiguous command")
+)
+
+// runCommand runs one action, an error is returned if the action fails, is not found, or is given
+// the wrong number of arguments
+func runCommand(context *app.AppContext, action string, params []string) error {
+	item, err := actions.Find(action)
+	if err != nil {
+		return err
+	}
+	if err := item.CheckArgs(params); err != nil {
+		return err
+	}
+	// Ctrl-C stops the action instead of the program
+	ctx, stop := context.Interruptible()
+	defer stop()
+	metadata, err := item.Action(context.WithCtx(ctx), params...)
+	applyMetadata(context, metadata)
+	return err
+}
+
+// parseInput splits a line into commands, reporting any problem with the quoting
+func parseInput(context *app.AppContext, line string) ([]lib.Command, error) {
+	commands, err := lib.ParseCommands(line)
+	if err != nil {
+		fmt.Fprintln(context.Error, lib.WrapText(lib.Codes{lib.ESC_RED}, "Could not read the command:"), err)
+	}
+	return commands, err
+}
+
+// addBody adds text, from several lines of input or a pipe, to the end of the last command as one
+// argument
+func addBody(commands []lib.Command, body string) []lib.Command {
+	if body == "" || len(commands) == 0 {
+		return commands
+	}
+	last := len(commands) - 1
+	commands[last].Words = append(commands[last].Words, body)
+	return commands
+}
+
+// runCommands runs each command, reporting any errors. When stopOnError is set the remaining
+// commands are skipped after a failure, otherwise only those joined with "&&" are. The last error
+// is returned.
+func runCommands(context *app.AppContext, commands []lib.Command, stopOnError bool) error {
+	var lastErr error
+	failed := false
+	for _, command := range commands {
+		if command.IfSuccess && failed {
+			continue
+		}
+		err := runCommand(context, command.Words[0], command.Words[1:])
+		failed = err != nil
+		if err != nil {
+			reportError(context, err)
+			lastErr = err
+			if stopOnError {
+				return err
+			}
+		}
+	}
+	return lastErr
+}
+
+func reportError(context *app.AppContext, err error) {
+	if errors.Is(err, errInvalidOption) {
+		fmt.Fprintln(context.Error, lib.WrapText(lib.Codes{lib.ESC_RED}, lib.Capitalize(err.Error())+"."))
+		if context.Ask != nil {
+			displayMenu()
+		}
+		return
+	}
+	if errors.Is(err, app.ErrInterrupted) {
+		fmt.Fprintln(context.Error, lib.WrapText(lib.Codes{lib.ESC_YELLOW}, "\nInterrupted."))
+		return
+	}
+	if errors.Is(err, errAmbiguous) {
+		fmt.Fprintln(context.Error, lib.WrapText(lib.Codes{lib.ESC_RED}, lib.Capitalize(err.Error())+"."))
+		return
+	}
+	//action reported an error, print it out
+	fmt.Fprintln(context.Error, lib.WrapText(lib.Codes{lib.ESC_RED}, "Error executing action:"), err)
+}
+
+// runBatch runs commands from -c, or a single command from the remaining command line arguments
+// which keeps any quoted arguments together. When the last command takes a prompt, piped input is
+// added to the end of it so it can be used as the prompt. Other commands leave stdin alone, so they
+// can not be broken by, or wait on, input which was never meant for them. Returns the exit code
+// for the program.
+func runBatch(context *app.AppContext, rawCommands string, args []string) int {
+	commands := []lib.Command{{Words: args}}
+	if rawCommands != "" {
+		var err error
+		if commands, err = parseInput(context, rawCommands); err != nil {
+			return 1
+		}
+	}
+	if len(commands) == 0 {
+		return 0
+	}
+
+	if takesPrompt(commands[len(commands)-1]) {
+		piped, err := readPipedInput()
+		if err != nil {
+			fmt.Fprintln(context.Error, err)
+			return 1
+		}
+		commands = addBody(commands, piped)
+	}
+
+	if err := runCommands(context, commands, true); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// promptActions are the actions which piped input can be given to
+var promptActions = []string{"Generate", "Chat", "Embed"}
+
+// takesPrompt is true if the command is one of the promptActions
+func takesPrompt(command lib.Command) bool {
+	item, err := actions.Find(command.Words[0])
+	return err == nil && slices.Contains(promptActions, item.Name)
+}
+
+// readPipedInput returns everything on standard input when it is a pipe or file, and an empty
+// string when it is a terminal.
+func readPipedInput() (string, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+		return "", nil
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func setup_liner(line *liner.State, context *app.AppContext) string {
 	//set up liner for command line input with history and tab completion
 	history_fn := filepath.Join(os.TempDir(), ".ollama-server_history") //used by liner
 
 	line.SetCtrlCAborts(true)
 
 	line.SetTabCompletionStyle(liner.TabPrints)
-	line.SetCompleter(func(line string) (c []string) {
-		for _, n := range actions.Triggers() {
-			if strings.HasPrefix(n, strings.ToLower(line)) {
-				c = append(c, n)
-				return
-			}
-		}
-		return
-	})
+	line.SetWordCompleter((&completer{state: context}).Complete)
 	if f, err := os.Open(history_fn); err == nil {
 		line.ReadHistory(f)
 		f.Close()
@@ -191,80 +498,79 @@ func main() {
 	actions.UpdateAction("Help", DisplayMenu)
 
 	context := app.AppContext{
-		HostName: ollamaServerURL1,
+		HostName: "",
 		Output:   os.Stdout,
 		Error:    os.Stderr,
 		Context:  nil,
+
+		Conversation: &app.Conversation{},
+		Options:      app.Options{},
+		Tools:        &app.Toolbox{},
+		Attachments:  &app.Attachments{},
+		Last:         &app.LastAnswer{},
+		Stats:        &app.SessionStats{},
 	}
 
-	var initAction string
-	flag.StringVar(&context.HostName, "host", ollamaServerURL2, "Ollama server host URL")
+	var initAction, hostFlag, profileName, batchCommands string
+	flag.StringVar(&hostFlag, "host", "", "Ollama server host URL, overrides OLLAMA_HOST and the profile. Defaults to "+ollamaServerURL2)
 	flag.StringVar(&initAction, "action", "", "Initial action to execute. Defaults to 'help'.")
+	flag.StringVar(&batchCommands, "c", "", "Commands to run, separated by ';' or '&&', then exit. A single command can also be given after the flags.")
+	flag.StringVar(&profileName, "profile", "", "Profile from the configuration file to use")
+	flag.StringVar(&context.Format, "output", app.FormatTable, "Output format: json, yaml or table")
+	flag.IntVar(&context.Verbose, "verbose", 0, "Verbosity, 1 shows prompts before they are sent, 2 adds debug logging")
 	flag.Parse()
+	if context.Verbose > 1 {
+		lib.EnableDebug()
+	}
+	if err := app.ValidFormat(context.Format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	setupHost(&context, hostFlag, profileName)
+
+	// batch mode runs the commands given on the command line and exits, without the banner or menu
+	if batchCommands != "" || flag.NArg() > 0 {
+		os.Exit(runBatch(&context, batchCommands, flag.Args()))
+	}
 
-	var rawChoice string //the raw command line input from the user, which may contain multiple commands separated by ";". We will split it up and execute each command in order. If no input is given, we will default to "help" to display the menu.
+	var commands []lib.Command //the commands from the user, which may be several separated by ";" or "&&". Each is run in order. If no input is given, we will default to "help" to display the menu.
 
 	//do initial action before asking for user input, if none given, then default to help
 	initAction = strings.TrimSpace(initAction)
 	if initAction != "" {
-		rawChoice = initAction
+		commands, _ = parseInput(&context, initAction)
 	} else {
-		rawChoice = "help"
+		commands, _ = parseInput(&context, "help")
 	}
 
 	line := liner.NewLiner()
 	defer line.Close()
-	history := setup_liner(line)
+	history := setup_liner(line, &context)
+	context.Ask = line.Prompt
+	// Markdown and the stats footer are only the defaults when interactive, batch mode keeps the
+	// raw answer with no footer after it so scripts get the text as it was written
+	context.Render = app.RenderMarkdown
+	context.Stats.Enabled = true
 
 	fmt.Println(lib.WrapText(lib.Codes{lib.ESC_BOLD, lib.ESC_UNDERLINE, lib.ESC_BLUE},
 		"Ollama Server Command Line"))
 	fmt.Println("By Thomas.Cherry.gmail.com (https://github.com/jceaser)")
 	fmt.Println()
 	for { //event loop
-		if len(rawChoice) > 0 {
-			multipleChoices := strings.Split(rawChoice, ";")
-			for _, choice := range multipleChoices {
-				splitChoice := strings.Fields(strings.TrimSpace(choice))
-				action := splitChoice[0]
-				params := splitChoice[1:]
-
-				found := false //true when an action has been found in the list
-				// Check if the action matches any of the actionable items
-				for _, a := range actions {
-					if a.Matches(action) {
-						metadata, err := a.Action(context, params...)
-						if err != nil {
-							//action reported an error, print it out
-							fmt.Println(lib.WrapText(lib.Codes{lib.ESC_RED}, "Error executing action:"), err)
-						}
-						// If the action returns metadata, check if it contains a "context" key and
-						//  update the AppContext accordingly
-						if metadata != nil {
-							if sessionContext, okay := metadata["context"]; okay {
-								intArray, err := jsonToIntArray(sessionContext)
-								if err == nil {
-									context.Context = intArray
-								}
-							}
-						}
-						found = true
-						break
-					}
-				}
-				if !found {
-					msg := fmt.Sprintf("Invalid option [%s] with %v.\n", action, params)
-					fmt.Println(lib.WrapText(lib.Codes{lib.ESC_RED}, msg))
-					displayMenu()
-				}
-			}
+		if len(commands) > 0 {
+			runCommands(&context, commands, false)
 		}
 
 		//set up for the next loop
 		//rawChoice = askForChoice()
-		rawChoice = askForCommand(line)
+		command, body := askForCommand(line)
+		commands, _ = parseInput(&context, command)
+		commands = addBody(commands, body)
 
-		line.AppendHistory(rawChoice)
-		saveHistory(line, history)
+		if entry := historyEntry(command, body); entry != "" {
+			line.AppendHistory(entry)
+			saveHistory(line, history)
+		}
 
 	}
 }